@@ -2,7 +2,12 @@ package main
 
 import (
 	"fmt"
+	"github.com/pantrif/s2-geojson/internal/app/controllers"
 	"github.com/pantrif/s2-geojson/internal/app/server"
+	"github.com/pantrif/s2-geojson/pkg/logger"
+	"os"
+	"strconv"
+	"time"
 )
 
 const (
@@ -10,6 +15,55 @@ const (
 )
 
 func main() {
+	if lvl := os.Getenv("LOG_LEVEL"); lvl != "" {
+		level, err := logger.ParseLevel(lvl)
+		if err != nil {
+			fmt.Printf("failed to init: %v", err)
+			return
+		}
+		logger.SetLevel(level)
+	}
+	for env, level := range map[string]*int{
+		"DEFAULT_MIN_LEVEL": &controllers.DefaultMinLevel,
+		"DEFAULT_MAX_LEVEL": &controllers.DefaultMaxLevel,
+	} {
+		if v := os.Getenv(env); v != "" {
+			l, err := strconv.Atoi(v)
+			if err != nil || l < 0 || l > 30 {
+				fmt.Printf("failed to init: %s must be a level between 0 and 30", env)
+				return
+			}
+			*level = l
+		}
+	}
+	if controllers.DefaultMinLevel > controllers.DefaultMaxLevel {
+		fmt.Printf("failed to init: DEFAULT_MIN_LEVEL greater than DEFAULT_MAX_LEVEL")
+		return
+	}
+	if v := os.Getenv("RATE_LIMIT"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			fmt.Printf("failed to init: %v", err)
+			return
+		}
+		server.RateLimit = rate
+	}
+	if v := os.Getenv("RATE_BURST"); v != "" {
+		burst, err := strconv.Atoi(v)
+		if err != nil {
+			fmt.Printf("failed to init: %v", err)
+			return
+		}
+		server.RateBurst = burst
+	}
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			fmt.Printf("failed to init: %v", err)
+			return
+		}
+		server.ShutdownTimeout = timeout
+	}
 	if err := server.Init(rootPath); err != nil {
 		fmt.Printf("failed to init: %v", err)
 	}
@@ -1,25 +1,1705 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
 	"github.com/pantrif/s2-geojson/pkg/geo"
+	"github.com/paulmach/go.geojson"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// Default covering levels applied when a request omits them, set at startup to tune a deployment
+var (
+	DefaultMinLevel = 1
+	DefaultMaxLevel = 16
+)
+
+// maxCellLevel is the level of the s2 leaf cells
+const maxCellLevel = 30
+
+// facesLevel is the max level of the coarse covering determining the faces of a geometry
+const facesLevel = 4
+
+// maxTileZoomOffset is the max number of zoom levels above the level of a cell when listing its tiles,
+// polar cells spanning all longitudes still overlap whole rows of tiles, bounded by geo.MaxTiles
+const maxTileZoomOffset = 7
+
+// defaultPrecision is the default number of decimals of the cell coordinates, about 1cm
+const defaultPrecision = 7
+
+// defaultMaxCellsCircle is the default max number of cells of the circle covering of CheckIntersection
+const defaultMaxCellsCircle = 300
+
+// maxSmoothIterations is the max number of smoothing iterations, each one doubling the vertices of the rings
+const maxSmoothIterations = 5
+
+// defaultCircleSegments and maxCircleSegments are the default and max number of vertices of a circle polygon
+const (
+	defaultCircleSegments = 64
+	maxCircleSegments     = 1024
+)
+
+// maxCircleRadius is the max radius in meters of a circle polygon, about a quarter of the circumference
+const maxCircleRadius = 10000000
+
+// maxSnapDecimals is the max number of decimals of the snapped coordinates, below the float64 precision
+const maxSnapDecimals = 15
+
+// maxBufferCells is the max number of rings of neighbor cells added around each polygon covering
+const maxBufferCells = 10
+
 // GeometryController struct
 type GeometryController struct{}
 
-// Cover uses s2 region coverer to cover geometries of geojson (only points and polygons supported)
-func (u GeometryController) Cover(c *gin.Context) {
-	gJSON := []byte(c.PostForm("geojson"))
-	maxLevel, err := strconv.Atoi(c.PostForm("max_level_geojson"))
-	minLevel, err := strconv.Atoi(c.PostForm("min_level_geojson"))
+// levelParam parses the level form field, returning def when the field is absent
+func levelParam(c *gin.Context, field string, def int) (int, error) {
+	v := c.PostForm(field)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// precisionParam parses the precision form field, the number of decimals of the cell coordinates
+// defaulting to 7, a negative precision keeps the full precision
+func precisionParam(c *gin.Context) (int, error) {
+	return levelParam(c, "precision", defaultPrecision)
+}
+
+// coordOrderParam parses the coord_order form field, returning whether the cell vertices are emitted in
+// the GeoJSON lng,lat order. The default latlng order is kept for the existing clients.
+func coordOrderParam(c *gin.Context) (bool, error) {
+	switch c.PostForm("coord_order") {
+	case "", "latlng":
+		return false, nil
+	case "lnglat":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid coord_order %q, expected latlng or lnglat", c.PostForm("coord_order"))
+}
+
+// cellFormat is how the cell vertices of a response are written
+type cellFormat struct {
+	precision int
+	lngLat    bool
+	// mercator reprojects the vertices to web mercator meters, x taking the place of lng and y of lat
+	mercator bool
+}
+
+// cellFormatParams parses the precision, coord_order and output_srid form fields, the srid is 4326 or 3857
+func cellFormatParams(c *gin.Context) (cellFormat, error) {
+	precision, err := precisionParam(c)
+	if err != nil {
+		return cellFormat{}, err
+	}
+	lngLat, err := coordOrderParam(c)
+	if err != nil {
+		return cellFormat{}, err
+	}
+	f := cellFormat{precision: precision, lngLat: lngLat}
+	switch srid := c.PostForm("output_srid"); srid {
+	case "", "4326":
+	case "3857":
+		f.mercator = true
+	default:
+		return cellFormat{}, fmt.Errorf("unsupported output_srid %q, expected 4326 or 3857", srid)
+	}
+	return f, nil
+}
+
+// cells reprojects, orders and rounds the lat,lng cell vertices
+func (f cellFormat) cells(cells [][][]float64) [][][]float64 {
+	if f.lngLat || f.mercator {
+		cells = geo.LngLatCells(cells)
+	}
+	if f.mercator {
+		cells = geo.ReprojectWGS84ToMercator(cells)
+		if !f.lngLat {
+			cells = geo.LngLatCells(cells)
+		}
+	}
+	return geo.RoundCells(cells, f.precision)
+}
+
+// floatParam parses the optional float form field, returning 0 when the field is absent
+func floatParam(c *gin.Context, field string) (float64, error) {
+	v := c.PostForm(field)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// decodeFeatures decodes the features of the geojson form field, or of the topojson or wkt field when given
+func decodeFeatures(c *gin.Context) ([]*geojson.Feature, error) {
+	if tJSON := c.PostForm("topojson"); tJSON != "" {
+		return geo.DecodeTopoJSON([]byte(tJSON))
+	}
+	if wkt := c.PostForm("wkt"); wkt != "" {
+		g, err := geo.DecodeWKT(wkt)
+		if err != nil {
+			return nil, err
+		}
+		return []*geojson.Feature{geojson.NewFeature(g)}, nil
+	}
+	return geo.DecodeGeoJSON([]byte(c.PostForm("geojson")))
+}
+
+// bboxParam parses the optional comma separated west,south,east,north bbox of the form field
+func bboxParam(c *gin.Context, field string) (s2.Rect, bool, error) {
+	v := c.PostForm(field)
+	if v == "" {
+		return s2.EmptyRect(), false, nil
+	}
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return s2.EmptyRect(), false, fmt.Errorf("%s: expected west,south,east,north", field)
+	}
+	var bbox [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return s2.EmptyRect(), false, fmt.Errorf("%s: %v", field, err)
+		}
+		bbox[i] = f
+	}
+	r, err := geo.BBoxToRect(bbox[1], bbox[0], bbox[3], bbox[2])
+	if err != nil {
+		return s2.EmptyRect(), false, fmt.Errorf("%s: %v", field, err)
+	}
+	return r, true, nil
+}
+
+// decodeTokens decodes the comma separated cell tokens of the form field to a cell union
+func decodeTokens(c *gin.Context, field string) (s2.CellUnion, error) {
+	var cu s2.CellUnion
+	for _, t := range strings.Split(c.PostForm(field), ",") {
+		id := s2.CellIDFromToken(strings.TrimSpace(t))
+		if !id.IsValid() {
+			return nil, fmt.Errorf("%s: invalid token %q", field, t)
+		}
+		cu = append(cu, id)
+	}
+	return cu, nil
+}
+
+// coverOptions holds the parameters of a covering request
+type coverOptions struct {
+	maxLevel int
+	minLevel int
+	// maxCells is the maximum number of cells per polygon covering, 0 uses the default
+	maxCells int
+	// minCellArea is the area in square meters below which cells are merged upwards, 0 keeps all cells
+	minCellArea float64
+	repair      bool
+	// trustWinding covers the rings as wound, or else clockwise rings are reversed instead of covering their complement
+	trustWinding bool
+	// densify is the maximum edge length in meters of the polygons, 0 disables densification
+	densify float64
+	// simplify is the simplification tolerance in meters, 0 disables simplification
+	simplify float64
+	// simplifyTopology selects the simplification that never introduces self-intersections
+	simplifyTopology bool
+	// hull covers the convex hulls of the rings instead of the rings
+	hull bool
+	// smooth is the number of Chaikin smoothing iterations of the rings, 0 disables smoothing
+	smooth int
+	// snapDecimals is the number of decimals the coordinates of the rings are rounded to, 0 disables snapping
+	snapDecimals int
+	// merge unions the coverings of all features to a single normalized covering
+	merge bool
+	// coarsen is the maximum number of cells of the merged covering, 0 disables coarsening
+	coarsen int
+	// borderBand covers the band of the polygons within this many meters of their boundary, 0 covers the polygons
+	borderBand float64
+	// buffer grows each polygon covering by this many rings of edge neighbor cells of its boundary
+	buffer int
+	// uniform covers the polygons with cells of exactly the max level
+	uniform bool
+	// complement covers the part of the rect outside the polygons instead of the polygons when not nil
+	complement *s2.Rect
+	// clip keeps only the part of the covering within the rect when not nil
+	clip *s2.Rect
+	// center sorts the cells by the distance of their centers to the point when not nil
+	center *geo.Point
+	// verify drops the cells of the polygon coverings not intersecting their polygon
+	verify bool
+	// boundaryOnly keeps only the cells of the covering on the outline of the covered region
+	boundaryOnly bool
+	// tokensOnly skips computing the vertices of the cells
+	tokensOnly bool
+}
+
+// coverResult holds the covering of the features with the respective tokens and cells
+type coverResult struct {
+	covering s2.CellUnion
+	tokens   []string
+	cells    [][][]float64
+	// warnings reports the skipped empty features and degenerate rings
+	warnings []string
+	// features holds the covering of each covered feature before the coverings are merged
+	features []featureCovering
+	// dropped is the number of polygon covering cells dropped by the verification
+	dropped int
+	// polygonArea is the area of the covered polygons in square meters, 0 when the covering does not
+	// cover the polygons as a whole such as a complement, border band or boundary only covering
+	polygonArea float64
+}
+
+// featureCovering is the covering of a feature, with the feature id to correlate it to its source
+type featureCovering struct {
+	Feature int         `json:"feature"`
+	ID      interface{} `json:"id,omitempty"`
+	Tokens  []string    `json:"cell_tokens"`
+}
+
+// coverFeatures covers the points and polygons of the features, skipping empty geometries with a warning
+func coverFeatures(fs []*geojson.Feature, o coverOptions) (coverResult, error) {
+	var res coverResult
+	var polygons []*s2.Polygon
+	snap := -1
+	if o.snapDecimals > 0 {
+		snap = o.snapDecimals
+	}
+
+	for i, f := range fs {
+		rings, positions := geo.PolygonRings(f.Geometry), geo.PointPositions(f.Geometry)
+		if len(rings) == 0 && len(positions) == 0 {
+			res.warnings = append(res.warnings, fmt.Sprintf("feature %d: empty geometry skipped", i))
+			continue
+		}
+		start := len(res.covering)
+
+		for _, p := range rings {
+			if o.repair {
+				var err error
+				if p, err = geo.RepairPolygon(p); err != nil {
+					return coverResult{}, err
+				}
+			}
+			if o.hull {
+				p = geo.ConvexHull(p)
+			}
+			if o.simplify > 0 {
+				if o.simplifyTopology {
+					p = geo.SimplifyPolygonSafe(p, o.simplify)
+				} else {
+					p = geo.SimplifyPolygon(p, o.simplify)
+				}
+			}
+			if o.smooth > 0 {
+				p = geo.SmoothPolygon(p, o.smooth)
+			}
+			if o.densify > 0 {
+				p = geo.DensifyPolygon(p, o.densify)
+			}
+			if !o.trustWinding {
+				p = geo.FixRingWinding(p)
+			}
+			p, err := geo.PointsToPolygonSnapped(p, snap)
+			if err != nil {
+				res.warnings = append(res.warnings, fmt.Sprintf("feature %d: %v, ring skipped", i, err))
+				continue
+			}
+			polygons = append(polygons, p)
+			if o.complement == nil && o.clip == nil && o.borderBand == 0 && !o.boundaryOnly {
+				res.polygonArea += geo.PolygonArea(p)
+			}
+			var cu s2.CellUnion
+			if o.borderBand > 0 {
+				if cu, err = geo.CoverBorderBand(p, o.borderBand, o.maxLevel, o.minLevel); err != nil {
+					return coverResult{}, fmt.Errorf("feature %d: border_band_meters: %v", i, err)
+				}
+			} else if o.uniform {
+				if cu, err = geo.CoverPolygonUniform(p, o.maxLevel); err != nil {
+					return coverResult{}, fmt.Errorf("feature %d: uniform_level %d: %v", i, o.maxLevel, err)
+				}
+			} else {
+				cu = geo.CoverPolygonPresetIDs(p, geo.Preset{
+					MinLevel:    o.minLevel,
+					MaxLevel:    o.maxLevel,
+					MaxCells:    o.maxCells,
+					MinCellArea: o.minCellArea,
+				})
+			}
+			if o.verify {
+				var dropped int
+				cu, dropped = geo.VerifyCovering(p, cu)
+				res.dropped += dropped
+			}
+			if o.buffer > 0 {
+				cu = geo.BufferCovering(cu, o.buffer)
+				if o.uniform {
+					cu.Denormalize(o.maxLevel, 1)
+				}
+			}
+			res.covering = append(res.covering, cu...)
+		}
+		for _, pt := range positions {
+			res.covering = append(res.covering, geo.PointCellID(geo.Point{Lat: pt[1], Lng: pt[0]}, o.maxLevel))
+		}
+		res.features = append(res.features, featureCovering{
+			Feature: i,
+			ID:      f.ID,
+			Tokens:  geo.CellUnionToTokens(res.covering[start:]),
+		})
+	}
+	if o.complement != nil {
+		res.covering = geo.CoverComplement(*o.complement, polygons, o.maxLevel, o.minLevel)
+	}
+	if o.clip != nil {
+		res.covering = geo.ClipCovering(res.covering, *o.clip, o.maxLevel, o.minLevel)
+		if o.uniform {
+			res.covering.Denormalize(o.maxLevel, 1)
+		}
+	}
+
+	if o.coarsen > 0 {
+		res.covering = geo.CoarsenCovering(res.covering, o.coarsen)
+	} else if o.merge {
+		res.covering = s2.CellUnionFromUnion(res.covering)
+		if o.uniform {
+			res.covering.Denormalize(o.maxLevel, 1)
+		}
+	}
+	if o.boundaryOnly {
+		res.covering = geo.BoundaryCells(res.covering)
+	}
+	if o.center != nil {
+		res.covering = geo.SortByDistance(res.covering, *o.center)
+	}
+
+	if o.tokensOnly {
+		res.tokens = geo.CellUnionToTokens(res.covering)
+	} else {
+		res.tokens, res.cells = geo.CellUnionTokens(res.covering)
+	}
+	return res, nil
+}
+
+// Cover uses s2 region coverer to cover geometries of geojson (only points and polygons supported).
+// The max_level_geojson and min_level_geojson levels default to DefaultMaxLevel and DefaultMinLevel when
+// omitted, or to the levels of the fast, balanced or accurate preset when given.
+// The format geojson responds with a feature collection of the cells, geobuf with its geobuf encoding,
+// with the geometry multipolygon the collection has a single feature with a polygon for each cell.
+// With include_input the input features are added to the collection with the source property set.
+// With paths the face/child positions path of each cell is returned as well, with tokens_only the cells are omitted.
+// With per_feature the covering of each feature before merging is returned as well with the feature id.
+// With group_by_level the tokens are returned by level as cells_by_level as well, e.g. to draw coarse cells first.
+// With buffer_cells each polygon covering is grown by as many rings of neighbor cells of its boundary, so the
+// separate coverings of adjacent polygons overlap instead of leaving gaps along the shared border.
+// With adjacency the graph of the edge adjacent cells of the covering is returned by token as well.
+// With hull the convex hulls of the rings are covered, a coarse and always valid footprint for filtering.
+// With smooth the rings are rounded by as many iterations of Chaikin smoothing after simplifying, e.g. for
+// jagged hand drawn polygons.
+// With snap_decimals the coordinates of the rings are rounded to that many decimals before covering, collapsing
+// the near-coincident vertices caused by measurement jitter.
+// With verify the cells not intersecting their polygon are dropped before buffering, counted as verify_dropped.
+// The envelope bare responds with a top level array of the token and cell of each cell instead of the object,
+// the geojson and geobuf formats are always bare.
+// With cell_shape rect each cell is returned as its lat/lng bounding rectangle in degrees instead of its
+// vertices, simpler for bbox math though it slightly overstates the cell, the most near the poles.
+// The format binary responds with the cell ids packed by geo.MarshalCellUnion, the most compact encoding.
+// Rings winding clockwise are reversed unless assume_rfc7946_winding is set, in which case they are covered
+// as the complement of the region they outline.
+// Elevations of 3D coordinates are ignored by the covering, their min and max are returned as elevation_range.
+// The overcoverage_ratio of the covering area to the polygon area measures how tightly the covering fits.
+// With boundary_only only the cells on the outline of the covering are returned.
+// With border_band_meters only the band of the polygons within that distance of their boundary is covered,
+// failing for bands that take more than geo.MaxBandCells cells to resolve at the max level.
+// With center_lat and center_lng the cells are sorted by the distance of their centers to the point, nearest first.
+// With uniform_level the levels are ignored and all cells are of that level, even when merged. Polygons
+// estimated to have more than geo.MaxUniformCells cells at that level are rejected.
+// With the west,south,east,north complement_bbox the part of the bbox outside the polygons is covered instead,
+// as the difference of the bbox covering and the polygon interior coverings it is only as accurate as the max level.
+// Features whose bbox does not overlap the optional west,south,east,north window are skipped.
+// With the west,south,east,north clip_bbox only the part of the covering within the bbox is returned, e.g. for
+// the viewport of a map, its cells along the bbox edges may reach outside it.
+func (u GeometryController) Cover(c *gin.Context) {
+	format := c.PostForm("format")
+	if format != "" && format != "geojson" && format != "geobuf" && format != "binary" {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("unsupported format %q", format),
+		})
+		return
+	}
+	geometry := c.PostForm("geometry")
+	if geometry != "" && geometry != "cells" && geometry != "multipolygon" {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("unsupported geometry %q", geometry),
+		})
+		return
+	}
+	envelope := c.PostForm("envelope")
+	if envelope != "" && envelope != "object" && envelope != "bare" {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("unsupported envelope %q", envelope),
+		})
+		return
+	}
+	cellShape := c.PostForm("cell_shape")
+	if cellShape != "" && cellShape != "polygon" && cellShape != "rect" {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("unsupported cell_shape %q, expected polygon or rect", cellShape),
+		})
+		return
+	}
+
+	preset := geo.Presets["balanced"]
+	preset.MinLevel, preset.MaxLevel = DefaultMinLevel, DefaultMaxLevel
+	if name := c.PostForm("preset"); name != "" {
+		var err error
+		if preset, err = geo.LookupPreset(name); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	maxLevel, err := levelParam(c, "max_level_geojson", preset.MaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level_geojson", preset.MinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	uniform := c.PostForm("uniform_level") != ""
+	if uniform {
+		level, err := levelParam(c, "uniform_level", 0)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if level < 0 || level > maxCellLevel {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("uniform_level must be between 0 and %d", maxCellLevel),
+			})
+			return
+		}
+		maxLevel, minLevel = level, level
+	}
+	repair := c.PostForm("repair") == "true"
+
+	fs, err := decodeFeatures(c)
+
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	densify, err := floatParam(c, "densify")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	simplify, err := floatParam(c, "simplify")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minCellArea, err := floatParam(c, "min_cell_area_sqm")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	borderBand, err := floatParam(c, "border_band_meters")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	coarsen, err := levelParam(c, "coarsen", 0)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	smooth, err := levelParam(c, "smooth", 0)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if smooth < 0 || smooth > maxSmoothIterations {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("smooth must be between 0 and %d", maxSmoothIterations),
+		})
+		return
+	}
+	snapDecimals, err := levelParam(c, "snap_decimals", 0)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if snapDecimals < 0 || snapDecimals > maxSnapDecimals {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("snap_decimals must be between 0 and %d", maxSnapDecimals),
+		})
+		return
+	}
+	buffer, err := levelParam(c, "buffer_cells", 0)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if buffer < 0 || buffer > maxBufferCells {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("buffer_cells must be between 0 and %d", maxBufferCells),
+		})
+		return
+	}
+	out, err := cellFormatParams(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	window, ok, err := bboxParam(c, "window")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if ok {
+		// skip the features whose bbox does not overlap the window before covering
+		var inWindow []*geojson.Feature
+		for _, f := range fs {
+			if geo.FeatureRect(f).Intersects(window) {
+				inWindow = append(inWindow, f)
+			}
+		}
+		fs = inWindow
+	}
+
+	var center *geo.Point
+	if c.PostForm("center_lat") != "" || c.PostForm("center_lng") != "" {
+		lat, err := strconv.ParseFloat(c.PostForm("center_lat"), 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("center_lat: %v", err),
+			})
+			return
+		}
+		lng, err := strconv.ParseFloat(c.PostForm("center_lng"), 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("center_lng: %v", err),
+			})
+			return
+		}
+		center = &geo.Point{Lat: lat, Lng: lng}
+	}
+
+	complementRect, ok, err := bboxParam(c, "complement_bbox")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	var complement *s2.Rect
+	if ok {
+		complement = &complementRect
+	}
+
+	clipRect, ok, err := bboxParam(c, "clip_bbox")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	var clip *s2.Rect
+	if ok {
+		clip = &clipRect
+	}
+
+	tokensOnly := c.PostForm("tokens_only") == "true"
+	res, err := coverFeatures(fs, coverOptions{
+		maxLevel:         maxLevel,
+		minLevel:         minLevel,
+		maxCells:         preset.MaxCells,
+		minCellArea:      minCellArea,
+		repair:           repair,
+		densify:          densify,
+		simplify:         simplify,
+		simplifyTopology: c.PostForm("simplify_topology") == "true",
+		smooth:           smooth,
+		snapDecimals:     snapDecimals,
+		hull:             c.PostForm("hull") == "true",
+		trustWinding:     c.PostForm("assume_rfc7946_winding") == "true",
+		merge:            c.PostForm("merge") == "true",
+		coarsen:          coarsen,
+		borderBand:       borderBand,
+		buffer:           buffer,
+		uniform:          uniform,
+		complement:       complement,
+		clip:             clip,
+		center:           center,
+		verify:           c.PostForm("verify") == "true",
+		boundaryOnly:     c.PostForm("boundary_only") == "true",
+		tokensOnly:       tokensOnly || cellShape == "rect",
+	})
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if format == "binary" {
+		c.Data(200, "application/octet-stream", geo.MarshalCellUnion(res.covering))
+		return
+	}
+	if format != "" {
+		fc := geo.CellUnionToFeatureCollection(res.covering)
+		if geometry == "multipolygon" {
+			fc = geojson.NewFeatureCollection()
+			fc.AddFeature(geojson.NewFeature(geo.CellUnionToMultiPolygon(res.covering)))
+		}
+		if c.PostForm("include_input") == "true" {
+			for _, f := range fs {
+				f.SetProperty("source", true)
+				fc.AddFeature(f)
+			}
+		}
+		if format == "geojson" {
+			c.JSON(200, fc)
+			return
+		}
+		b, err := geo.EncodeGeobuf(fc)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Data(200, "application/x-protobuf", b)
+		return
+	}
+
+	if envelope == "bare" {
+		var cells [][][]float64
+		var bounds []geo.CellBounds
+		if !tokensOnly && cellShape == "rect" {
+			bounds = geo.CellUnionBounds(res.covering)
+		} else if !tokensOnly {
+			cells = out.cells(res.cells)
+		}
+		items := make([]gin.H, len(res.tokens))
+		for i, t := range res.tokens {
+			items[i] = gin.H{"token": t}
+			if bounds != nil {
+				items[i]["cell"] = bounds[i]
+			} else if cells != nil {
+				items[i]["cell"] = cells[i]
+			}
+		}
+		c.JSON(200, items)
+		return
+	}
+
+	edgeLengths := make([]float64, len(res.tokens))
+	for i, t := range res.tokens {
+		edgeLengths[i] = geo.CellEdgeLength(s2.CellIDFromToken(t).Level())
+	}
+
+	resp := gin.H{
+		"max_level_geojson": maxLevel,
+		"cell_tokens":       strings.Join(res.tokens, ","),
+		"cell_edge_lengths": edgeLengths,
+		"stats":             geo.CoveringStats(res.covering),
+		"is_global":         geo.IsGlobalCovering(res.covering),
+	}
+	if !tokensOnly && cellShape == "rect" {
+		resp["cells"] = geo.CellUnionBounds(res.covering)
+	} else if !tokensOnly {
+		resp["cells"] = out.cells(res.cells)
+	}
+	if res.polygonArea > 0 {
+		resp["overcoverage_ratio"] = geo.CoverageArea(res.covering) / res.polygonArea
+	}
+	if min, max, ok := geo.ElevationRange(fs); ok {
+		resp["elevation_range"] = []float64{min, max}
+	}
+	if c.PostForm("paths") == "true" {
+		paths := make([]string, len(res.tokens))
+		for i, t := range res.tokens {
+			paths[i] = geo.CellIDToPath(s2.CellIDFromToken(t))
+		}
+		resp["cell_paths"] = paths
+	}
+	if c.PostForm("per_feature") == "true" {
+		resp["features"] = res.features
+	}
+	if c.PostForm("group_by_level") == "true" {
+		resp["cells_by_level"] = geo.GroupByLevel(res.covering)
+	}
+	if c.PostForm("verify") == "true" {
+		resp["verify_dropped"] = res.dropped
+	}
+	if c.PostForm("adjacency") == "true" {
+		resp["adjacency"] = geo.CoveringAdjacency(res.covering)
+	}
+	if len(res.warnings) > 0 {
+		resp["warnings"] = res.warnings
+	}
+	c.JSON(200, resp)
+}
+
+// batchCoverItem is a single geometry of a batch covering request with its own levels
+type batchCoverItem struct {
+	GeoJSON  json.RawMessage `json:"geojson"`
+	MaxLevel *int            `json:"max_level"`
+	MinLevel *int            `json:"min_level"`
+}
+
+// levels returns the levels of the item, defaulting to DefaultMaxLevel and DefaultMinLevel when omitted
+func (i batchCoverItem) levels() (maxLevel, minLevel int) {
+	maxLevel, minLevel = DefaultMaxLevel, DefaultMinLevel
+	if i.MaxLevel != nil {
+		maxLevel = *i.MaxLevel
+	}
+	if i.MinLevel != nil {
+		minLevel = *i.MinLevel
+	}
+	return maxLevel, minLevel
+}
+
+// BatchCover covers a JSON array of geojson geometries each with its own levels, preserving the input order
+func (u GeometryController) BatchCover(c *gin.Context) {
+	var items []batchCoverItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	results := make([]gin.H, len(items))
+	for i, item := range items {
+		gJSON := []byte(item.GeoJSON)
+		// the geojson may be given as an object or as an encoded string
+		var s string
+		if json.Unmarshal(gJSON, &s) == nil {
+			gJSON = []byte(s)
+		}
+
+		fs, err := geo.DecodeGeoJSON(gJSON)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("item %d: %v", i, err),
+			})
+			return
+		}
+
+		maxLevel, minLevel := item.levels()
+		res, err := coverFeatures(fs, coverOptions{maxLevel: maxLevel, minLevel: minLevel})
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("item %d: %v", i, err),
+			})
+			return
+		}
+
+		results[i] = gin.H{
+			"max_level":   maxLevel,
+			"min_level":   minLevel,
+			"cell_tokens": strings.Join(res.tokens, ","),
+			"cells":       geo.RoundCells(res.cells, defaultPrecision),
+		}
+		if len(res.warnings) > 0 {
+			results[i]["warnings"] = res.warnings
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"results": results,
+	})
+}
+
+// CheckIntersection checks intersection of geoJSON geometries with a point and with a circle, the circle
+// covering has at most max_cells_circle cells, 300 by default. The circle is fully inside when its covering
+// is contained by the covering of the tokens, an approximation at the level of the cells.
+func (u GeometryController) CheckIntersection(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.PostForm("lat"), 64)
+	lng, err := strconv.ParseFloat(c.PostForm("lng"), 64)
+	radius, err := strconv.ParseFloat(c.PostForm("radius"), 64)
+
+	tk := c.PostForm("tokens")
+
+	maxLevelCircle, err := strconv.Atoi(c.PostForm("max_level_circle"))
+
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	maxCellsCircle, err := levelParam(c, "max_cells_circle", defaultMaxCellsCircle)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if maxCellsCircle < 1 {
+		c.JSON(400, gin.H{
+			"error": "max_cells_circle must be at least 1",
+		})
+		return
+	}
+	out, err := cellFormatParams(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	circleCovering := geo.CoverCircle(geo.Point{Lat: lat, Lng: lng}, radius, maxLevelCircle, maxCellsCircle)
+
+	var values []string
+	var s2cells [][][]float64
+
+	for _, c := range circleCovering {
+		c1 := s2.CellFromCellID(s2.CellIDFromToken(c.ToToken()))
+
+		s2cells = append(s2cells, geo.EdgesOfCell(c1))
+
+		values = append(values, c.ToToken())
+	}
+
+	ll := s2.LatLngFromDegrees(lat, lng)
+	cell := s2.CellFromLatLng(ll)
+
+	intersectsPoint, intersectsCircle := false, false
+
+	var covering s2.CellUnion
+
+	tokens := strings.Split(tk, ",")
+
+	for _, t := range tokens {
+		covering = append(covering, s2.CellIDFromToken(t))
+	}
+	covering.Normalize()
+	if covering.IntersectsCell(cell) {
+		intersectsPoint = true
+	}
+	if covering.Intersects(circleCovering) {
+		intersectsCircle = true
+	}
+
+	c.JSON(200, gin.H{
+		"intersects_with_point":  intersectsPoint,
+		"intersects_with_circle": intersectsCircle,
+		"circle_fully_inside":    covering.Contains(circleCovering),
+		"radius":                 radius,
+		"cells":                  out.cells(s2cells),
+	})
+}
+
+// CheckPoints checks for each point of a JSON array whether it is contained by any of the geoJSON polygons
+func (u GeometryController) CheckPoints(c *gin.Context) {
+	fs, err := geo.DecodeGeoJSON([]byte(c.PostForm("geojson")))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var points []geo.Point
+	if err := json.Unmarshal([]byte(c.PostForm("points")), &points); err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fi := geo.NewFeatureIndex(fs)
+	intersects := make([]bool, len(points))
+	for i, p := range points {
+		intersects[i] = len(fi.ContainsPoint(p)) > 0
+	}
+
+	c.JSON(200, gin.H{
+		"intersects": intersects,
+	})
+}
+
+// ContainsAllPoints checks whether every point of a JSON array is contained by the geoJSON polygons,
+// e.g. all the pings of a device in its geofence, returning the indices of the points outside
+func (u GeometryController) ContainsAllPoints(c *gin.Context) {
+	fs, err := geo.DecodeGeoJSON([]byte(c.PostForm("geojson")))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var points []geo.Point
+	if err := json.Unmarshal([]byte(c.PostForm("points")), &points); err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	outside := geo.PointsOutside(geo.FeaturePolygons(fs), points)
+	c.JSON(200, gin.H{
+		"all_inside": len(outside) == 0,
+		"outside":    outside,
+	})
+}
+
+// SnapToGrid covers the polygon of each geojson feature with its holes and merges each covering to a polygon
+// aligned to the s2 cells, skipping the features that are not polygons
+func (u GeometryController) SnapToGrid(c *gin.Context) {
+	maxLevel, err := levelParam(c, "max_level_geojson", DefaultMaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level_geojson", DefaultMinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fc := geojson.NewFeatureCollection()
+	for i, f := range fs {
+		if len(geo.PolygonRings(f.Geometry)) == 0 {
+			continue
+		}
+		polygon, err := geo.GeometryToPolygon(f.Geometry)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("feature %d: %v", i, err),
+			})
+			return
+		}
+		covering, _, _, err := geo.CoverPolygon(polygon, maxLevel, minLevel)
+		if err != nil {
+			continue
+		}
+		gridded := geojson.NewFeature(geo.PolygonToGeoJSON(geo.CellUnionToPolygon(covering)))
+		gridded.ID = f.ID
+		gridded.Properties = f.Properties
+		geo.EnforceGeoJSONWinding(gridded)
+		fc.AddFeature(gridded)
+	}
+
+	c.JSON(200, fc)
+}
+
+// LocatePoint returns the ids and properties of the geojson features containing the point
+func (u GeometryController) LocatePoint(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.PostForm("lat"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.PostForm("lng"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	matches := []gin.H{}
+	for _, f := range fs {
+		if geo.FeatureContainsPoint(f, geo.Point{Lat: lat, Lng: lng}) {
+			matches = append(matches, gin.H{
+				"id":         f.ID,
+				"properties": f.Properties,
+			})
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"features": matches,
+	})
+}
+
+// NearestFeature returns the id, properties and distance in meters of the geojson feature whose polygon
+// boundary is closest to the point
+func (u GeometryController) NearestFeature(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.PostForm("lat"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.PostForm("lng"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	n, ok := geo.NewFeatureIndex(fs).NearestFeature(geo.Point{Lat: lat, Lng: lng})
+	if !ok {
+		c.JSON(400, gin.H{
+			"error": "no polygon features",
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"id":              fs[n.Feature].ID,
+		"properties":      fs[n.Feature].Properties,
+		"distance_meters": n.DistanceMeters,
+	})
+}
+
+// KNearestFeatures returns the ids, properties and distances in meters of the k geojson features whose
+// polygon boundaries are closest to the point, sorted by distance
+func (u GeometryController) KNearestFeatures(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.PostForm("lat"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.PostForm("lng"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	k, err := strconv.Atoi(c.PostForm("k"))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if k < 1 {
+		c.JSON(400, gin.H{
+			"error": "k must be positive",
+		})
+		return
+	}
+
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	neighbors := []gin.H{}
+	for _, n := range geo.NewFeatureIndex(fs).KNearestFeatures(geo.Point{Lat: lat, Lng: lng}, k) {
+		neighbors = append(neighbors, gin.H{
+			"id":              fs[n.Feature].ID,
+			"properties":      fs[n.Feature].Properties,
+			"distance_meters": n.DistanceMeters,
+		})
+	}
+
+	c.JSON(200, gin.H{
+		"features": neighbors,
+	})
+}
+
+// decodePolygon decodes the first feature of the geojson form field as s2 polygon
+func decodePolygon(c *gin.Context, field string) (*s2.Polygon, error) {
+	fs, err := geo.DecodeGeoJSON([]byte(c.PostForm(field)))
+	if err != nil {
+		return nil, err
+	}
+	if len(fs) == 0 {
+		return nil, fmt.Errorf("%s: no features", field)
+	}
+	p, err := geo.GeometryToPolygon(fs[0].Geometry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", field, err)
+	}
+	return p, nil
+}
+
+// CoverOverlap covers the region where the first polygons of the geojson_a and geojson_b fields overlap,
+// an empty covering when they do not, between the max_level_geojson and min_level_geojson levels
+func (u GeometryController) CoverOverlap(c *gin.Context) {
+	maxLevel, err := levelParam(c, "max_level_geojson", DefaultMaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level_geojson", DefaultMinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	out, err := cellFormatParams(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	a, err := decodePolygon(c, "geojson_a")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	b, err := decodePolygon(c, "geojson_b")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	tokens, s2cells := geo.CellUnionTokens(geo.CoverOverlap(a, b, maxLevel, minLevel))
+	c.JSON(200, gin.H{
+		"max_level_geojson": maxLevel,
+		"cell_tokens":       strings.Join(tokens, ","),
+		"cells":             out.cells(s2cells),
+	})
+}
+
+// IntersectionMatrix returns the matrix of which polygon features of the geojson intersect which, failing
+// for features that are not polygons
+func (u GeometryController) IntersectionMatrix(c *gin.Context) {
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	polygons := make([]*s2.Polygon, len(fs))
+	ids := make([]interface{}, len(fs))
+	for i, f := range fs {
+		if polygons[i], err = geo.GeometryToPolygon(f.Geometry); err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("feature %d: %v", i, err),
+			})
+			return
+		}
+		ids[i] = f.ID
+	}
+
+	c.JSON(200, gin.H{
+		"ids":    ids,
+		"matrix": geo.IntersectionMatrix(polygons),
+	})
+}
+
+// ContainsPolygon checks if the outer geoJSON polygon entirely contains the inner one
+func (u GeometryController) ContainsPolygon(c *gin.Context) {
+	outer, err := decodePolygon(c, "outer")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	inner, err := decodePolygon(c, "inner")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"contains": geo.PolygonContainsPolygon(outer, inner),
+	})
+}
+
+// HoverCell returns the token and corners of the cell of the level containing the lat/lng query point,
+// without decoding any geometries as it is called on every mouse move of the map
+func (u GeometryController) HoverCell(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.Query("lng"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	level, err := strconv.Atoi(c.Query("level"))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if level < 0 || level > maxCellLevel {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("level must be between 0 and %d", maxCellLevel),
+		})
+		return
+	}
+
+	id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)).Parent(level)
+	c.JSON(200, gin.H{
+		"token": id.ToToken(),
+		"cell":  geo.RoundCells([][][]float64{geo.EdgesOfCell(s2.CellFromCellID(id))}, defaultPrecision)[0],
+	})
+}
+
+// LevelForCellSize returns the level whose average cell edge is closest to the meters query parameter with
+// that edge length, e.g. for about 500m cells
+func (u GeometryController) LevelForCellSize(c *gin.Context) {
+	meters, err := strconv.ParseFloat(c.Query("meters"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if meters <= 0 {
+		c.JSON(400, gin.H{
+			"error": "meters must be positive",
+		})
+		return
+	}
+
+	level := geo.LevelForCellSize(meters)
+	c.JSON(200, gin.H{
+		"level":            level,
+		"cell_edge_length": geo.CellEdgeLength(level),
+	})
+}
+
+// CellInfo returns the level, center, corners and lat/lng bounding rectangle of the cell of the token,
+// a single cell lookup that does not go through the covering
+func (u GeometryController) CellInfo(c *gin.Context) {
+	id := s2.CellIDFromToken(c.Query("token"))
+	if !id.IsValid() {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid token %q", c.Query("token")),
+		})
+		return
+	}
+
+	cell := s2.CellFromCellID(id)
+	center := id.LatLng()
+	c.JSON(200, gin.H{
+		"token":  id.ToToken(),
+		"level":  id.Level(),
+		"center": geo.Point{Lat: center.Lat.Degrees(), Lng: center.Lng.Degrees()},
+		"cell":   geo.RoundCells([][][]float64{geo.EdgesOfCell(cell)}, defaultPrecision)[0],
+		"bounds": geo.BoundsOfCell(cell),
+	})
+}
+
+// CoverBBox covers the min_lat, min_lng, max_lat, max_lng bounding box, the max_level and min_level
+// levels default to DefaultMaxLevel and DefaultMinLevel when omitted
+func (u GeometryController) CoverBBox(c *gin.Context) {
+	var bbox [4]float64
+	for i, field := range []string{"min_lat", "min_lng", "max_lat", "max_lng"} {
+		v, err := strconv.ParseFloat(c.PostForm(field), 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("%s: %v", field, err),
+			})
+			return
+		}
+		bbox[i] = v
+	}
+	maxLevel, err := levelParam(c, "max_level", DefaultMaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level", DefaultMinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	out, err := cellFormatParams(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	rect, err := geo.BBoxToRect(bbox[0], bbox[1], bbox[2], bbox[3])
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	_, tokens, s2cells := geo.CoverRect(rect, maxLevel, minLevel)
+
+	c.JSON(200, gin.H{
+		"max_level":   maxLevel,
+		"min_level":   minLevel,
+		"cell_tokens": strings.Join(tokens, ","),
+		"cells":       out.cells(s2cells),
+	})
+}
+
+// CoverDiff covers the geojson geometries with the max levels level_a and level_b and returns the tokens
+// of the cells added and removed going from the level_a to the level_b covering
+func (u GeometryController) CoverDiff(c *gin.Context) {
+	levelA, err := strconv.Atoi(c.PostForm("level_a"))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	levelB, err := strconv.Atoi(c.PostForm("level_b"))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level_geojson", DefaultMinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	a, err := coverFeatures(fs, coverOptions{maxLevel: levelA, minLevel: minLevel, merge: true})
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	b, err := coverFeatures(fs, coverOptions{maxLevel: levelB, minLevel: minLevel, merge: true})
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	added, removed := geo.CoveringDiff(a.covering, b.covering)
+	addedTokens, _ := geo.CellUnionTokens(added)
+	removedTokens, _ := geo.CellUnionTokens(removed)
+
+	c.JSON(200, gin.H{
+		"added":   addedTokens,
+		"removed": removedTokens,
+	})
+}
+
+// DiffTokens compares the tokens_a and tokens_b coverings, e.g. two versions of a geofence, returning the
+// tokens of the cells added and removed going from a to b and of their symmetric difference as changed
+func (u GeometryController) DiffTokens(c *gin.Context) {
+	a, err := decodeTokens(c, "tokens_a")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	b, err := decodeTokens(c, "tokens_b")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	added, removed := geo.CoveringDiff(a, b)
+	c.JSON(200, gin.H{
+		"added":   geo.CellUnionToTokens(added),
+		"removed": geo.CellUnionToTokens(removed),
+		"changed": geo.CellUnionToTokens(geo.CoveringSymmetricDifference(a, b)),
+	})
+}
+
+// CoverLine covers the line of the precision 5 encoded_polyline field, or the linestrings of the geojson
+// features, with cells between the max_level_geojson and min_level_geojson levels
+func (u GeometryController) CoverLine(c *gin.Context) {
+	maxLevel, err := levelParam(c, "max_level_geojson", DefaultMaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level_geojson", DefaultMinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	out, err := cellFormatParams(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var lines [][][]float64
+	if encoded := c.PostForm("encoded_polyline"); encoded != "" {
+		line, err := geo.DecodePolyline(encoded)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("encoded_polyline: %v", err),
+			})
+			return
+		}
+		lines = append(lines, line)
+	} else {
+		fs, err := decodeFeatures(c)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, f := range fs {
+			lines = append(lines, geo.LineStrings(f.Geometry)...)
+		}
+	}
+
+	var covering s2.CellUnion
+	for i, line := range lines {
+		if len(line) < 2 {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("line %d: less than 2 positions", i),
+			})
+			return
+		}
+		covering = append(covering, geo.CoverLine(line, maxLevel, minLevel)...)
+	}
+	if len(covering) == 0 {
+		c.JSON(400, gin.H{
+			"error": "no lines",
+		})
+		return
+	}
+	tokens, s2cells := geo.CellUnionTokens(covering)
+
+	c.JSON(200, gin.H{
+		"max_level_geojson": maxLevel,
+		"cell_tokens":       strings.Join(tokens, ","),
+		"cells":             out.cells(s2cells),
+	})
+}
+
+// CirclePolygon returns the geojson polygon of segments vertices, 64 by default, approximating the geodesic
+// circle of the radius in meters around lat/lng for clients that cannot use caps. With cover the polygon is
+// covered as well between the max_level_geojson and min_level_geojson levels.
+func (u GeometryController) CirclePolygon(c *gin.Context) {
+	var values [3]float64
+	for i, field := range []string{"lat", "lng", "radius"} {
+		v, err := strconv.ParseFloat(c.PostForm(field), 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("%s: %v", field, err),
+			})
+			return
+		}
+		values[i] = v
+	}
+	center, radius := geo.Point{Lat: values[0], Lng: values[1]}, values[2]
+	if radius <= 0 || radius > maxCircleRadius {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("radius must be positive and at most %d meters", maxCircleRadius),
+		})
+		return
+	}
+	segments, err := levelParam(c, "segments", defaultCircleSegments)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if segments < 3 || segments > maxCircleSegments {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("segments must be between 3 and %d", maxCircleSegments),
+		})
+		return
+	}
 
-	fs, err := geo.DecodeGeoJSON(gJSON)
+	ring := geo.CirclePolygon(center, radius, segments)
+	resp := gin.H{
+		"polygon": geojson.NewPolygonGeometry([][][]float64{ring}),
+	}
+	if c.PostForm("cover") == "true" {
+		maxLevel, err := levelParam(c, "max_level_geojson", DefaultMaxLevel)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		minLevel, err := levelParam(c, "min_level_geojson", DefaultMinLevel)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		out, err := cellFormatParams(c)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		p, err := geo.PointsToPolygon(ring)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		_, tokens, s2cells, err := geo.CoverPolygon(p, maxLevel, minLevel)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		resp["cell_tokens"] = strings.Join(tokens, ",")
+		resp["cells"] = out.cells(s2cells)
+	}
+	c.JSON(200, resp)
+}
 
+// CoverAnnulus covers the ring between the inner_radius and outer_radius in meters around lat/lng, e.g. for
+// proximity zones between 1km and 5km. The level and min_level levels default to DefaultMaxLevel and
+// DefaultMinLevel when omitted.
+func (u GeometryController) CoverAnnulus(c *gin.Context) {
+	var values [4]float64
+	for i, field := range []string{"lat", "lng", "inner_radius", "outer_radius"} {
+		v, err := strconv.ParseFloat(c.PostForm(field), 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("%s: %v", field, err),
+			})
+			return
+		}
+		values[i] = v
+	}
+	center, inner, outer := geo.Point{Lat: values[0], Lng: values[1]}, values[2], values[3]
+	if inner < 0 || outer <= inner || outer > maxCircleRadius {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("radii must satisfy 0 <= inner_radius < outer_radius <= %d meters", maxCircleRadius),
+		})
+		return
+	}
+	maxLevel, err := levelParam(c, "level", DefaultMaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level", DefaultMinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	out, err := cellFormatParams(c)
 	if err != nil {
 		c.JSON(400, gin.H{
 			"error": err.Error(),
@@ -27,44 +1707,179 @@ func (u GeometryController) Cover(c *gin.Context) {
 		return
 	}
 
-	var tokens []string
-	var s2cells [][][]float64
+	tokens, s2cells := geo.CellUnionTokens(geo.CoverAnnulus(center, inner, outer, maxLevel, minLevel))
+	c.JSON(200, gin.H{
+		"level":       maxLevel,
+		"min_level":   minLevel,
+		"cell_tokens": strings.Join(tokens, ","),
+		"cells":       out.cells(s2cells),
+	})
+}
 
-	for _, f := range fs {
+// CellsAlongLine returns for each line of the precision 5 encoded_polyline field, or of the linestrings of the
+// geojson features, the cells of the level it passes through in traversal order, each cell at its first visit.
+// Lines estimated to pass through more than geo.MaxLineCells cells in total are rejected.
+func (u GeometryController) CellsAlongLine(c *gin.Context) {
+	level, err := levelParam(c, "level", DefaultMaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if level < 0 || level > maxCellLevel {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("level must be between 0 and %d", maxCellLevel),
+		})
+		return
+	}
+	out, err := cellFormatParams(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-		if f.Geometry.IsPolygon() {
-			for _, p := range f.Geometry.Polygon {
-				p := geo.PointsToPolygon(p)
-				_, t, c := geo.CoverPolygon(p, maxLevel, minLevel)
-				s2cells = append(s2cells, c...)
-				tokens = append(tokens, t...)
-			}
+	var lines [][][]float64
+	if encoded := c.PostForm("encoded_polyline"); encoded != "" {
+		line, err := geo.DecodePolyline(encoded)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("encoded_polyline: %v", err),
+			})
+			return
+		}
+		lines = append(lines, line)
+	} else {
+		fs, err := decodeFeatures(c)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, f := range fs {
+			lines = append(lines, geo.LineStrings(f.Geometry)...)
+		}
+	}
+	if len(lines) == 0 {
+		c.JSON(400, gin.H{
+			"error": "no lines",
+		})
+		return
+	}
+
+	total := 0.0
+	for i, line := range lines {
+		if len(line) < 2 {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("line %d: less than 2 positions", i),
+			})
+			return
+		}
+		total += geo.LineCellCount(line, level)
+	}
+	if total > geo.MaxLineCells {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("%v: the lines pass through about %.0f cells of level %d, at most %d", geo.ErrTooManyCells, total, level, geo.MaxLineCells),
+		})
+		return
+	}
+
+	paths := make([]gin.H, len(lines))
+	for i, line := range lines {
+		cells, err := geo.CellsAlongLine(line, level)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("line %d: %v", i, err),
+			})
+			return
 		}
-		if f.Geometry.IsPoint() {
-			point := geo.Point{Lat: f.Geometry.Point[1], Lng: f.Geometry.Point[0]}
-			_, t, c := geo.CoverPoint(point, maxLevel)
-			s2cells = append(s2cells, c...)
-			tokens = append(tokens, t)
+		tokens, s2cells := geo.CellUnionTokens(cells)
+		paths[i] = gin.H{
+			"cell_tokens": strings.Join(tokens, ","),
+			"cells":       out.cells(s2cells),
 		}
 	}
 
 	c.JSON(200, gin.H{
-		"max_level_geojson": maxLevel,
-		"cell_tokens":       strings.Join(tokens, ","),
-		"cells":             s2cells,
+		"level": level,
+		"lines": paths,
 	})
 }
 
-// CheckIntersection checks intersection of geoJSON geometries with a point and with a circle
-func (u GeometryController) CheckIntersection(c *gin.Context) {
-	lat, err := strconv.ParseFloat(c.PostForm("lat"), 64)
-	lng, err := strconv.ParseFloat(c.PostForm("lng"), 64)
-	radius, err := strconv.ParseFloat(c.PostForm("radius"), 64)
+// CoverMVT covers the geojson geometries and responds with the covering cells clipped to the z/x/y tile
+// encoded as mapbox vector tile
+func (u GeometryController) CoverMVT(c *gin.Context) {
+	maxLevel, err := levelParam(c, "max_level_geojson", DefaultMaxLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	minLevel, err := levelParam(c, "min_level_geojson", DefaultMinLevel)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	var tile [3]int
+	for i, field := range []string{"z", "x", "y"} {
+		if tile[i], err = strconv.Atoi(c.PostForm(field)); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
 
-	tk := c.PostForm("tokens")
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	maxLevelCircle, err := strconv.Atoi(c.PostForm("max_level_circle"))
+	res, err := coverFeatures(fs, coverOptions{maxLevel: maxLevel, minLevel: minLevel, tokensOnly: true})
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	b, err := geo.CellUnionToMVT(res.covering, tile[0], tile[1], tile[2])
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Data(200, "application/vnd.mapbox-vector-tile", b)
+}
 
+// CoveringContainsPoint checks if the covering of the comma separated tokens contains the lat/lng point.
+// The check is cell accurate, not edge accurate, as it does not rebuild the covered polygons.
+func (u GeometryController) CoveringContainsPoint(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.PostForm("lat"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.PostForm("lng"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	cu, err := decodeTokens(c, "tokens")
 	if err != nil {
 		c.JSON(400, gin.H{
 			"error": err.Error(),
@@ -72,46 +1887,217 @@ func (u GeometryController) CheckIntersection(c *gin.Context) {
 		return
 	}
 
-	angle := s1.Angle((radius / 1000) / geo.EarthRadius)
-	ca := s2.CapFromCenterAngle(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng)), angle)
-	circeCov := &s2.RegionCoverer{MaxLevel: maxLevelCircle, MaxCells: 300}
-	circleRegion := s2.Region(ca)
-	circleCovering := circeCov.Covering(circleRegion)
+	c.JSON(200, gin.H{
+		"contains": geo.CellUnionContainsPoint(cu, geo.Point{Lat: lat, Lng: lng}),
+	})
+}
 
-	var values []string
-	var s2cells [][][]float64
+// TokensToPolygon merges the cells of the comma separated tokens and responds with a geojson feature of the
+// covered outline, a multipolygon when the cells are disconnected
+func (u GeometryController) TokensToPolygon(c *gin.Context) {
+	if _, err := decodeTokens(c, "tokens"); err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	for _, c := range circleCovering {
-		c1 := s2.CellFromCellID(s2.CellIDFromToken(c.ToToken()))
+	p := geo.TokensToPolygon(strings.Split(c.PostForm("tokens"), ","))
+	f := geojson.NewFeature(geo.PolygonToGeoJSON(p))
+	geo.EnforceGeoJSONWinding(f)
 
-		s2cells = append(s2cells, geo.EdgesOfCell(c1))
+	c.JSON(200, f)
+}
 
-		values = append(values, c.ToToken())
+// MinimalEnclosingCell returns the token, level and polygon of the smallest cell containing all the geojson
+// geometries, failing when they are empty or span several cube faces
+func (u GeometryController) MinimalEnclosingCell(c *gin.Context) {
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	ll := s2.LatLngFromDegrees(lat, lng)
-	cell := s2.CellFromLatLng(ll)
+	var id s2.CellID
+	for i, f := range fs {
+		fid := geo.MinimalEnclosingCell(f.Geometry)
+		if !fid.IsValid() {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("feature %d: no cell encloses the geometry", i),
+			})
+			return
+		}
+		if id == 0 {
+			id = fid
+			continue
+		}
+		level, ok := id.CommonAncestorLevel(fid)
+		if !ok {
+			c.JSON(400, gin.H{
+				"error": "the geometries span several cube faces",
+			})
+			return
+		}
+		id = id.Parent(level)
+	}
+	if id == 0 {
+		c.JSON(400, gin.H{
+			"error": "no geometries",
+		})
+		return
+	}
 
-	intersectsPoint, intersectsCircle := false, false
+	f := geojson.NewFeature(geo.PolygonToGeoJSON(geo.CellUnionToPolygon(s2.CellUnion{id})))
+	geo.EnforceGeoJSONWinding(f)
+	c.JSON(200, gin.H{
+		"token":   id.ToToken(),
+		"level":   id.Level(),
+		"polygon": f.Geometry,
+	})
+}
 
-	var covering s2.CellUnion
+// CoveringTiles returns the z/x/y web mercator tiles of the zoom overlapping the cells of the comma
+// separated tokens, sorted by x and y
+func (u GeometryController) CoveringTiles(c *gin.Context) {
+	z, err := strconv.Atoi(c.PostForm("zoom"))
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if z < 0 || z > geo.MaxTileZoom {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("zoom must be between 0 and %d", geo.MaxTileZoom),
+		})
+		return
+	}
+	cu, err := decodeTokens(c, "tokens")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	tokens := strings.Split(tk, ",")
+	count := 0
+	for _, id := range cu {
+		if z-id.Level() > maxTileZoomOffset {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("zoom %d too high for the level %d cell %s", z, id.Level(), id.ToToken()),
+			})
+			return
+		}
+		count += geo.TileCount(id, z)
+	}
+	if count > geo.MaxTiles {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("%v: the cells overlap %d tiles, at most %d", geo.ErrTooManyTiles, count, geo.MaxTiles),
+		})
+		return
+	}
 
-	for _, t := range tokens {
-		covering = append(covering, s2.CellIDFromToken(t))
+	seen := make(map[geo.Tile]bool)
+	tiles := []geo.Tile{}
+	for _, id := range cu {
+		cellTiles, err := geo.CellToTiles(id, z)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, t := range cellTiles {
+			if !seen[t] {
+				seen[t] = true
+				tiles = append(tiles, t)
+			}
+		}
 	}
-	if covering.IntersectsCell(cell) {
-		intersectsPoint = true
+	sort.Slice(tiles, func(i, j int) bool {
+		if tiles[i].X != tiles[j].X {
+			return tiles[i].X < tiles[j].X
+		}
+		return tiles[i].Y < tiles[j].Y
+	})
+
+	c.JSON(200, gin.H{
+		"tiles": tiles,
+	})
+}
+
+// Inspect reports for each polygon ring of the geojson features its vertex count, whether it is closed,
+// its winding and whether it converts to a valid s2 loop, with the longest edge and min width of valid rings.
+// As the min width is quadratic it is skipped, flagged by min_width_skipped, for rings of more than
+// geo.MaxWidthVertices vertices and past geo.MaxWidthTotalVertices measured vertices.
+func (u GeometryController) Inspect(c *gin.Context) {
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
-	if covering.Intersects(circleCovering) {
-		intersectsCircle = true
+
+	c.JSON(200, gin.H{
+		"rings": geo.InspectFeatures(fs),
+	})
+}
+
+// Triangulate tessellates each polygon of the geojson features into triangles for rendering fills,
+// returning the vertices of its rings and the counterclockwise triangles indexing them
+func (u GeometryController) Triangulate(c *gin.Context) {
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ts, err := geo.TriangulateFeatures(fs)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
 	c.JSON(200, gin.H{
-		"intersects_with_point":  intersectsPoint,
-		"intersects_with_circle": intersectsCircle,
-		"radius":                 radius,
-		"cells":                  s2cells,
+		"polygons": ts,
 	})
 }
+
+// Faces returns the distinct s2 cube faces touched by the geojson geometries, with a warning when the
+// geometries span multiple faces
+func (u GeometryController) Faces(c *gin.Context) {
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	res, err := coverFeatures(fs, coverOptions{maxLevel: facesLevel, minLevel: 0})
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	faces := geo.CoveringFaces(res.covering)
+	resp := gin.H{
+		"faces": faces,
+	}
+	warnings := res.warnings
+	if len(faces) > 1 {
+		warnings = append(warnings, fmt.Sprintf("geometries span %d faces", len(faces)))
+	}
+	if len(warnings) > 0 {
+		resp["warnings"] = warnings
+	}
+	c.JSON(200, resp)
+}
@@ -1,12 +1,19 @@
 package controllers_test
 
 import (
+	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/geo/s2"
+	"github.com/pantrif/s2-geojson/internal/app/controllers"
 	"github.com/pantrif/s2-geojson/internal/app/server"
+	"github.com/pantrif/s2-geojson/pkg/geo"
+	"github.com/paulmach/go.geojson"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -82,28 +89,2457 @@ func TestCover(t *testing.T) {
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	r.ServeHTTP(w, req)
 	assert.Equal(t, 200, w.Result().StatusCode)
+
+	data.Set("repair", "true")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},
+		"geometry":{"type":"Polygon","coordinates":[[[0,0],[0,1],[1,1],[1,0]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	data.Del("repair")
+	data.Del("geojson")
+	data.Set("topojson", `{"type":"Topology","objects":{"a":{"type":"Polygon","arcs":[[0]]}},
+		"arcs":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	data.Del("topojson")
+	data.Set("wkt", "MULTIPOLYGON (((0 0, 1 0, 1 1, 0 1, 0 0)), ((5 5, 6 5, 6 6, 5 5)))")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	data.Set("densify", "10000")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	data.Set("simplify", "100")
+	data.Set("simplify_topology", "true")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	data.Del("simplify")
+	data.Del("simplify_topology")
+
+	data.Set("densify", "foo")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	data.Del("densify")
+
+	data.Set("wkt", "POLYGON ((0 0, 1 0")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverDefaultLevels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("geojson", string(validJSON))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		MaxLevel int `json:"max_level_geojson"`
+		Stats    struct {
+			MinLevel int `json:"min_level"`
+			MaxLevel int `json:"max_level"`
+		} `json:"stats"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 16, resp.MaxLevel)
+	assert.True(t, resp.Stats.MinLevel >= 1)
+	assert.Equal(t, 16, resp.Stats.MaxLevel)
+
+	data.Set("max_level_geojson", "foo")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverMerge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	// two adjacent squares sharing an edge
+	data := url.Values{}
+	data.Set("max_level_geojson", "8")
+	data.Set("min_level_geojson", "1")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[1,0],[2,0],[2,1],[1,1],[1,0]]]}}]}`)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	cover := func() []string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return strings.Split(resp.Tokens, ",")
+	}
+
+	separate := cover()
+	data.Set("merge", "true")
+	merged := cover()
+	assert.True(t, len(merged) < len(separate))
+
+	seen := map[string]bool{}
+	for _, tk := range merged {
+		assert.False(t, seen[tk])
+		seen[tk] = true
+	}
+}
+
+func TestCoverEmptyGeometries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[1,1]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[5,5],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens   string   `json:"cell_tokens"`
+		Warnings []string `json:"warnings"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Tokens)
+	assert.Equal(t, []string{
+		"feature 0: empty geometry skipped",
+		"feature 1: degenerate ring: less than 3 distinct vertices, ring skipped",
+		"feature 3: degenerate ring: less than 3 distinct vertices, ring skipped",
+	}, resp.Warnings)
+}
+
+func TestCoverCoarsen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "12")
+	data.Set("coarsen", "5")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, len(strings.Split(resp.Tokens, ",")) <= 5)
+}
+
+func TestCoverBoundaryOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("min_level_geojson", "10")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	cover := func() []string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+
+		var resp struct {
+			Tokens string `json:"cell_tokens"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return strings.Split(resp.Tokens, ",")
+	}
+	all := cover()
+	data.Set("boundary_only", "true")
+	boundary := cover()
+	assert.NotEmpty(t, boundary)
+	assert.True(t, len(boundary) < len(all))
+	assert.Subset(t, all, boundary)
+}
+
+func TestCoverUniformLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("uniform_level", "8")
+	data.Set("merge", "true")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	for _, token := range strings.Split(resp.Tokens, ",") {
+		assert.Equal(t, 8, s2.CellIDFromToken(token).Level())
+	}
+
+	data.Set("uniform_level", "31")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	// a grid of leaf cells over the square is rejected instead of computed
+	data.Set("uniform_level", "30")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"feature 0: uniform_level 30: too many cells\"}\n", w.Body.String())
+}
+
+func TestCoverComplementBBox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("complement_bbox", "0,0,3,3")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[1,1],[2,1],[2,2],[1,2],[1,1]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var cu s2.CellUnion
+	for _, token := range strings.Split(resp.Tokens, ",") {
+		cu = append(cu, s2.CellIDFromToken(token))
+	}
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(1.5, 1.5))))
+
+	data.Set("complement_bbox", "0,0,3")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverClipBBox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("clip_bbox", "1,1,2,2")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,4],[0,4],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var cu s2.CellUnion
+	for _, token := range strings.Split(resp.Tokens, ",") {
+		cu = append(cu, s2.CellIDFromToken(token))
+	}
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(1.5, 1.5))))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(3.5, 3.5))))
+	assert.NotContains(t, w.Body.String(), "overcoverage_ratio")
+
+	data.Set("clip_bbox", "1,1,2")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "4")
+	data.Set("envelope", "bare")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var items []struct {
+		Token string      `json:"token"`
+		Cell  [][]float64 `json:"cell"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	assert.Equal(t, 4, len(items))
+	assert.Equal(t, "055", items[0].Token)
+	assert.Equal(t, 4, len(items[0].Cell))
+
+	data.Set("tokens_only", "true")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `[{"token":"055"},{"token":"0ff"},{"token":"101"},{"token":"1ab"}]`+"\n", w.Body.String())
+
+	data.Set("envelope", "list")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverCellShape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "4")
+	data.Set("cell_shape", "rect")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string           `json:"cell_tokens"`
+		Cells  []geo.CellBounds `json:"cells"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 4, len(resp.Cells))
+	assert.Equal(t, geo.BoundsOfCell(s2.CellFromCellID(s2.CellIDFromToken("055"))), resp.Cells[0])
+
+	data.Set("envelope", "bare")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	var items []struct {
+		Token string         `json:"token"`
+		Cell  geo.CellBounds `json:"cell"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	assert.Equal(t, 4, len(items))
+	assert.Equal(t, resp.Cells[3], items[3].Cell)
+
+	data.Set("cell_shape", "hexagon")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverCenter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "4")
+	data.Set("tokens_only", "true")
+	data.Set("center_lat", "1")
+	data.Set("center_lng", "1")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	tokens := strings.Split(resp.Tokens, ",")
+	assert.ElementsMatch(t, []string{"055", "0ff", "101", "1ab"}, tokens)
+	ref := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 1))
+	for i := 1; i < len(tokens); i++ {
+		prev, cur := s2.CellIDFromToken(tokens[i-1]).Point(), s2.CellIDFromToken(tokens[i]).Point()
+		assert.True(t, prev.Distance(ref) <= cur.Distance(ref))
+	}
+
+	data.Del("center_lng")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverBorderBand(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "12")
+	data.Set("border_band_meters", "10000")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string        `json:"cell_tokens"`
+		Cells  [][][]float64 `json:"cells"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var cu s2.CellUnion
+	for _, token := range strings.Split(resp.Tokens, ",") {
+		cu = append(cu, s2.CellIDFromToken(token))
+	}
+	assert.Equal(t, len(cu), len(resp.Cells))
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.02))))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+
+	data.Set("max_level_geojson", "20")
+	data.Set("border_band_meters", "1")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"feature 0: border_band_meters: too many cells\"}\n", w.Body.String())
+
+	data.Set("border_band_meters", "wide")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "8")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	cover := func(format string) *httptest.ResponseRecorder {
+		data.Set("format", format)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := cover("geojson")
+	assert.Equal(t, 200, w.Result().StatusCode)
+	fc, err := geojson.UnmarshalFeatureCollection(w.Body.Bytes())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fc.Features)
+	assert.True(t, fc.Features[0].Geometry.IsPolygon())
+
+	w = cover("geobuf")
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Body.Bytes())
+
+	w = cover("binary")
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	cu, err := geo.UnmarshalCellUnion(w.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, len(fc.Features), len(cu))
+
+	data.Set("geometry", "multipolygon")
+	w = cover("geojson")
+	assert.Equal(t, 200, w.Result().StatusCode)
+	multi, err := geojson.UnmarshalFeatureCollection(w.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(multi.Features))
+	assert.True(t, multi.Features[0].Geometry.IsMultiPolygon())
+	assert.Equal(t, len(fc.Features), len(multi.Features[0].Geometry.MultiPolygon))
+
+	data.Set("include_input", "true")
+	w = cover("geojson")
+	assert.Equal(t, 200, w.Result().StatusCode)
+	withInput, err := geojson.UnmarshalFeatureCollection(w.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(withInput.Features))
+	assert.Nil(t, withInput.Features[0].Properties["source"])
+	assert.Equal(t, true, withInput.Features[1].Properties["source"])
+	assert.True(t, withInput.Features[1].Geometry.IsPolygon())
+	data.Del("include_input")
+
+	data.Set("geometry", "hexagons")
+	w = cover("geojson")
+	assert.Equal(t, 400, w.Result().StatusCode)
+	data.Del("geometry")
+
+	w = cover("kml")
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverPreset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	var resp struct {
+		MaxLevel int    `json:"max_level_geojson"`
+		Tokens   string `json:"cell_tokens"`
+	}
+	cover := func(preset string) int {
+		data.Set("preset", preset)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return len(strings.Split(resp.Tokens, ","))
+	}
+
+	fast := cover("fast")
+	assert.Equal(t, 12, resp.MaxLevel)
+	assert.True(t, fast <= 20)
+	accurate := cover("accurate")
+	assert.Equal(t, 20, resp.MaxLevel)
+	assert.True(t, accurate > fast)
+
+	// explicit levels take precedence over the preset
+	data.Set("max_level_geojson", "10")
+	cover("accurate")
+	assert.Equal(t, 10, resp.MaxLevel)
+
+	data.Set("preset", "slow")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestBatchCover(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/batch_cover", strings.NewReader("foo"))
+	req.Header.Add("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	body := `[{"geojson":` + string(validJSON) + `,"max_level":5,"min_level":2},
+		{"geojson":"{\"type\":\"FeatureCollection\",\"features\":[]}","max_level":3,"min_level":1}]`
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/batch_cover", strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Results []struct {
+			MaxLevel   int    `json:"max_level"`
+			CellTokens string `json:"cell_tokens"`
+		} `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, len(resp.Results))
+	assert.Equal(t, 5, resp.Results[0].MaxLevel)
+	assert.NotEmpty(t, resp.Results[0].CellTokens)
+	assert.Equal(t, 3, resp.Results[1].MaxLevel)
+	assert.Empty(t, resp.Results[1].CellTokens)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/batch_cover", strings.NewReader(`[{"geojson":`+string(validJSON)+`}]`))
+	req.Header.Add("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `"max_level":16`)
+	assert.Contains(t, w.Body.String(), `"min_level":1`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/batch_cover", strings.NewReader(`[{"geojson":"foo","max_level":3}]`))
+	req.Header.Add("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCheckIntersection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/check_intersection", nil)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data := url.Values{}
+	data.Set("radius", "1000")
+	data.Set("max_level_circle", "12")
+	data.Set("lat", "35.5666")
+	data.Set("lng", "23.4444")
+	data.Set("tokens", "48761ac,48761b4")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/check_intersection", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+}
+
+func TestCheckIntersectionFullyInside(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	// the cell around the circle center is an order of magnitude larger than the small circle
+	token := s2.CellIDFromLatLng(s2.LatLngFromDegrees(35.5666, 23.4444)).Parent(10).ToToken()
+	check := func(radius string) map[string]interface{} {
+		data := url.Values{}
+		data.Set("radius", radius)
+		data.Set("max_level_circle", "16")
+		data.Set("lat", "35.5666")
+		data.Set("lng", "23.4444")
+		data.Set("tokens", token)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/check_intersection", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	inside := check("50")
+	assert.Equal(t, true, inside["intersects_with_circle"])
+	assert.Equal(t, true, inside["circle_fully_inside"])
+	assert.NotContains(t, inside, "circle_fully_inside_note")
+
+	partial := check("50000")
+	assert.Equal(t, true, partial["intersects_with_circle"])
+	assert.Equal(t, false, partial["circle_fully_inside"])
+}
+
+func TestCheckIntersectionMaxCellsCircle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("radius", "100000")
+	data.Set("max_level_circle", "16")
+	data.Set("lat", "35.5666")
+	data.Set("lng", "23.4444")
+	data.Set("tokens", "48761ac,48761b4")
+	circleCells := func(maxCells string) (int, int) {
+		data.Set("max_cells_circle", maxCells)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/check_intersection", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		var resp struct {
+			Cells [][][]float64 `json:"cells"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Result().StatusCode, len(resp.Cells)
+	}
+
+	status, n := circleCells("")
+	assert.Equal(t, 200, status)
+	assert.True(t, n > 20 && n <= 300)
+	status, n = circleCells("8")
+	assert.Equal(t, 200, status)
+	assert.True(t, n <= 8)
+	status, _ = circleCells("0")
+	assert.Equal(t, 400, status)
+	status, _ = circleCells("many")
+	assert.Equal(t, 400, status)
+}
+
+func TestCheckPoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/check_points", nil)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data := url.Values{}
+	data.Set("geojson", string(validJSON))
+	data.Set("points", `[{"lat":39.5,"lng":-90.5},{"lat":35.5666,"lng":23.4444}]`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/check_points", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"intersects\":[true,false]}\n", w.Body.String())
+
+	// the points in the hole and far outside of a polygon with a hole are not in it
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon",
+		"coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[4,6],[6,6],[6,4],[4,4]]]}}]}`)
+	data.Set("points", `[{"lat":2,"lng":2},{"lat":5,"lng":5},{"lat":50,"lng":100}]`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/check_points", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"intersects\":[true,false,false]}\n", w.Body.String())
+}
+
+func TestContainsAllPoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	post := func(points string) *httptest.ResponseRecorder {
+		data := url.Values{}
+		data.Set("geojson", string(validJSON))
+		data.Set("points", points)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/contains_all_points", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`[{"lat":39.5,"lng":-90.5},{"lat":35.5666,"lng":23.4444}]`)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"all_inside\":false,\"outside\":[1]}\n", w.Body.String())
+
+	w = post(`[{"lat":39.5,"lng":-90.5}]`)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"all_inside\":true,\"outside\":[]}\n", w.Body.String())
+
+	w = post(`{`)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	// a geofence with a hole, the pings in the hole and far away are outside
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon",
+		"coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[4,6],[6,6],[6,4],[4,4]]]}}]}`)
+	data.Set("points", `[{"lat":2,"lng":2},{"lat":5,"lng":5},{"lat":50,"lng":100}]`)
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/contains_all_points", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"all_inside\":false,\"outside\":[1,2]}\n", w.Body.String())
+}
+
+func TestSnapToGrid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/snap_to_grid", nil)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "5")
+	data.Set("min_level_geojson", "2")
+	data.Set("geojson", string(validJSON))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/snap_to_grid", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	fc, err := geojson.UnmarshalFeatureCollection(w.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fc.Features))
+	assert.True(t, fc.Features[0].Geometry.IsPolygon())
+
+	// the hole of a polygon stays out of its gridded polygon
+	data.Set("max_level_geojson", "8")
+	data.Set("min_level_geojson", "1")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon",
+		"coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[3,3],[3,7],[7,7],[7,3],[3,3]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/snap_to_grid", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	fc, err = geojson.UnmarshalFeatureCollection(w.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fc.Features))
+	gridded, err := geo.GeometryToPolygon(fc.Features[0].Geometry)
+	assert.NoError(t, err)
+	at := func(lat, lng float64) bool {
+		return gridded.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng)))
+	}
+	assert.True(t, at(1, 1))
+	assert.False(t, at(5, 5))
+	assert.False(t, at(50, 100))
+
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,1],[0,0]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/snap_to_grid", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestLocatePoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/locate_point", nil)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data := url.Values{}
+	data.Set("lat", "0.5")
+	data.Set("lng", "0.5")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"a","properties":{"name":"A"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","id":"b","properties":{"name":"B"},"geometry":{"type":"Polygon","coordinates":[[[5,5],[6,5],[6,6],[5,6],[5,5]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/locate_point", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"features\":[{\"id\":\"a\",\"properties\":{\"name\":\"A\"}}]}\n", w.Body.String())
+
+	// a zone with a hole matches neither the points in its hole nor the points far outside
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"z","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[4,6],[6,6],[6,4],[4,4]]]}}]}`)
+	for _, pt := range [][2]string{{"2", "2"}, {"5", "5"}, {"50", "100"}} {
+		data.Set("lat", pt[0])
+		data.Set("lng", pt[1])
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/locate_point", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		if pt[0] == "2" {
+			assert.Equal(t, "{\"features\":[{\"id\":\"z\",\"properties\":{}}]}\n", w.Body.String())
+		} else {
+			assert.Equal(t, "{\"features\":[]}\n", w.Body.String())
+		}
+	}
+}
+
+func TestNearestFeature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/nearest_feature", nil)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data := url.Values{}
+	data.Set("lat", "4")
+	data.Set("lng", "5.5")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"a","properties":{"name":"A"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","id":"b","properties":{"name":"B"},"geometry":{"type":"Polygon","coordinates":[[[5,5],[6,5],[6,6],[5,6],[5,5]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/nearest_feature", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var nearest struct {
+		ID             string            `json:"id"`
+		Properties     map[string]string `json:"properties"`
+		DistanceMeters float64           `json:"distance_meters"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &nearest))
+	assert.Equal(t, "b", nearest.ID)
+	assert.Equal(t, "B", nearest.Properties["name"])
+	assert.InDelta(t, 111195, nearest.DistanceMeters, 100)
+
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[0,0]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/nearest_feature", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestKNearestFeatures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("lat", "4")
+	data.Set("lng", "5.5")
+	data.Set("k", "0")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"a","properties":{"name":"A"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","id":"b","properties":{"name":"B"},"geometry":{"type":"Polygon","coordinates":[[[5,5],[6,5],[6,6],[5,6],[5,5]]]}},
+		{"type":"Feature","id":"c","properties":{"name":"C"},"geometry":{"type":"Polygon","coordinates":[[[20,20],[21,20],[21,21],[20,21],[20,20]]]}}]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/k_nearest_features", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data.Set("k", "2")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/k_nearest_features", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var nearest struct {
+		Features []struct {
+			ID             string  `json:"id"`
+			DistanceMeters float64 `json:"distance_meters"`
+		} `json:"features"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &nearest))
+	assert.Len(t, nearest.Features, 2)
+	assert.Equal(t, "b", nearest.Features[0].ID)
+	assert.Equal(t, "a", nearest.Features[1].ID)
+	assert.True(t, nearest.Features[0].DistanceMeters < nearest.Features[1].DistanceMeters)
+}
+
+func TestContainsPolygon(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/contains_polygon", nil)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data := url.Values{}
+	data.Set("outer", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},
+		"geometry":{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}}]}`)
+	data.Set("inner", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},
+		"geometry":{"type":"Polygon","coordinates":[[[1,1],[2,1],[2,2],[1,2],[1,1]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/contains_polygon", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"contains\":true}\n", w.Body.String())
+
+	data.Set("inner", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},
+		"geometry":{"type":"Polygon","coordinates":[[[1,1],[2,2],[1,1]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/contains_polygon", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"inner: degenerate ring: less than 3 distinct vertices\"}\n", w.Body.String())
+
+	data.Set("inner", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},
+		"geometry":{"type":"Polygon","coordinates":[[]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/contains_polygon", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"inner: empty ring\"}\n", w.Body.String())
+}
+
+func TestCoverOverlap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("geojson_a", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[2,0],[2,2],[0,2],[0,0]]]}}]}`)
+	data.Set("geojson_b", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[1,1],[3,1],[3,3],[1,3],[1,1]]]}}]}`)
+	overlap := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover_overlap", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := overlap()
+	assert.Equal(t, 200, w.Result().StatusCode)
+	var resp struct {
+		Tokens string        `json:"cell_tokens"`
+		Cells  [][][]float64 `json:"cells"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, len(strings.Split(resp.Tokens, ",")), len(resp.Cells))
+	inside := s2.CellIDFromLatLng(s2.LatLngFromDegrees(1.5, 1.5))
+	covered := false
+	for _, tk := range strings.Split(resp.Tokens, ",") {
+		covered = covered || s2.CellIDFromToken(tk).Contains(inside)
+	}
+	assert.True(t, covered)
+
+	data.Set("geojson_b", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[10,10],[11,10],[11,11],[10,11],[10,10]]]}}]}`)
+	w = overlap()
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"cell_tokens\":\"\",\"cells\":[],\"max_level_geojson\":10}\n", w.Body.String())
+
+	data.Set("geojson_b", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[1,1],[1,1],[1,1],[1,1]]]}}]}`)
+	w = overlap()
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data.Del("geojson_b")
+	w = overlap()
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestIntersectionMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"a","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[2,0],[2,2],[0,2],[0,0]]]}},
+		{"type":"Feature","id":"b","properties":{},"geometry":{"type":"Polygon","coordinates":[[[1,1],[3,1],[3,3],[1,3],[1,1]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"MultiPolygon","coordinates":[[[[10,10],[11,10],[11,11],[10,11],[10,10]]]]}}]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/intersection_matrix", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"ids\":[\"a\",\"b\",null],\"matrix\":[[true,true,false],[true,true,false],[false,false,true]]}\n", w.Body.String())
+
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[0,0]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/intersection_matrix", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"feature 0: geometry is not a polygon\"}\n", w.Body.String())
+
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/intersection_matrix", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"feature 0: empty ring\"}\n", w.Body.String())
+}
+
+func TestHoverCell(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hover_cell?lat=38.34&lng=34.34&level=10", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Token string      `json:"token"`
+		Cell  [][]float64 `json:"cell"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "14d607", resp.Token)
+	assert.Equal(t, 4, len(resp.Cell))
+
+	for _, q := range []string{"lat=x&lng=34.34&level=10", "lat=38.34&lng=34.34", "lat=38.34&lng=34.34&level=31"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/hover_cell?"+q, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Result().StatusCode)
+	}
+}
+
+func TestCellInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cell_info?token=14d607", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Token  string `json:"token"`
+		Level  int    `json:"level"`
+		Center struct {
+			Lat float64 `json:"lat"`
+			Lng float64 `json:"lng"`
+		} `json:"center"`
+		Cell   [][]float64        `json:"cell"`
+		Bounds map[string]float64 `json:"bounds"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "14d607", resp.Token)
+	assert.Equal(t, 10, resp.Level)
+	assert.Equal(t, 4, len(resp.Cell))
+	assert.True(t, resp.Bounds["min_lat"] < 38.34 && 38.34 < resp.Bounds["max_lat"])
+	assert.True(t, resp.Bounds["min_lng"] < 34.34 && 34.34 < resp.Bounds["max_lng"])
+	assert.True(t, resp.Bounds["min_lat"] < resp.Center.Lat && resp.Center.Lat < resp.Bounds["max_lat"])
+	assert.True(t, resp.Bounds["min_lng"] < resp.Center.Lng && resp.Center.Lng < resp.Bounds["max_lng"])
+	for _, v := range resp.Cell {
+		assert.True(t, resp.Bounds["min_lat"] <= v[0]+1e-7 && v[0]-1e-7 <= resp.Bounds["max_lat"])
+		assert.True(t, resp.Bounds["min_lng"] <= v[1]+1e-7 && v[1]-1e-7 <= resp.Bounds["max_lng"])
+	}
+
+	for _, q := range []string{"", "token=", "token=zz"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/cell_info?"+q, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Result().StatusCode)
+	}
+}
+
+func TestLevelForCellSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/level_for_cell_size?meters=500", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	var resp struct {
+		Level  int     `json:"level"`
+		Length float64 `json:"cell_edge_length"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 14, resp.Level)
+	assert.InDelta(t, 567, resp.Length, 1)
+
+	for _, q := range []string{"", "meters=x", "meters=0", "meters=-5"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/level_for_cell_size?"+q, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Result().StatusCode)
+	}
+}
+
+func TestCoverBBox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("min_lat", "0")
+	data.Set("min_lng", "0")
+	data.Set("max_lat", "1")
+	data.Set("max_lng", "1")
+	data.Set("max_level", "8")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover_bbox", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		MaxLevel int           `json:"max_level"`
+		MinLevel int           `json:"min_level"`
+		Tokens   string        `json:"cell_tokens"`
+		Cells    [][][]float64 `json:"cells"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 8, resp.MaxLevel)
+	assert.Equal(t, 1, resp.MinLevel)
+	assert.Equal(t, len(strings.Split(resp.Tokens, ",")), len(resp.Cells))
+
+	data.Set("min_lat", "2")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_bbox", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data.Del("max_lng")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_bbox", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverPrecision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "8")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	var resp struct {
+		Cells [][][]float64 `json:"cells"`
+	}
+	cover := func(precision string) int {
+		data.Set("precision", precision)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Body.Len()
+	}
+
+	full := cover("-1")
+	rounded := cover("3")
+	assert.True(t, rounded < full)
+	for _, cell := range resp.Cells {
+		for _, v := range cell {
+			assert.InDelta(t, math.Round(v[0]*1000), v[0]*1000, 1e-6)
+			assert.InDelta(t, math.Round(v[1]*1000), v[1]*1000, 1e-6)
+		}
+	}
+	assert.True(t, cover("") < full)
+}
+
+func TestCoverOvercoverageRatio(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	ratio := func(maxLevel string) float64 {
+		data := url.Values{}
+		data.Set("max_level_geojson", maxLevel)
+		data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		var resp struct {
+			Ratio float64 `json:"overcoverage_ratio"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Ratio
+	}
+
+	coarse, fine := ratio("6"), ratio("12")
+	assert.True(t, fine >= 1)
+	assert.True(t, coarse > fine)
+
+	data := url.Values{}
+	data.Set("geojson", `{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[1,1]}}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.NotContains(t, w.Body.String(), "overcoverage_ratio")
+}
+
+func TestCoverElevation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	cover := func(geojson string) map[string]interface{} {
+		data := url.Values{}
+		data.Set("max_level_geojson", "8")
+		data.Set("geojson", geojson)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	flat := cover(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+	elevated := cover(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0,100],[1,0,120],[1,1,80],[0,1,100],[0,0,100]]]}}]}`)
+	assert.NotEmpty(t, flat["cell_tokens"])
+	assert.Equal(t, flat["cell_tokens"], elevated["cell_tokens"])
+	assert.Equal(t, []interface{}{80.0, 120.0}, elevated["elevation_range"])
+	assert.NotContains(t, flat, "elevation_range")
+}
+
+func TestCoverWinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	cover := func(ring string, trust string) map[string]interface{} {
+		data := url.Values{}
+		data.Set("max_level_geojson", "8")
+		data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[`+ring+`]}}]}`)
+		data.Set("assume_rfc7946_winding", trust)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	ccw, cw := "[[0,0],[1,0],[1,1],[0,1],[0,0]]", "[[0,0],[0,1],[1,1],[1,0],[0,0]]"
+	fixed := cover(cw, "")
+	assert.Equal(t, cover(ccw, "")["cell_tokens"], fixed["cell_tokens"])
+	assert.Equal(t, false, fixed["is_global"])
+
+	assert.Equal(t, true, cover(cw, "true")["is_global"])
+	assert.Equal(t, fixed["cell_tokens"], cover(ccw, "true")["cell_tokens"])
+}
+
+func TestCoverPerFeature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "6")
+	data.Set("per_feature", "true")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"park","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[]}},
+		{"type":"Feature","id":7,"properties":{},"geometry":{"type":"Point","coordinates":[20,20]}}]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Features []struct {
+			Feature int         `json:"feature"`
+			ID      interface{} `json:"id"`
+			Tokens  []string    `json:"cell_tokens"`
+		} `json:"features"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, len(resp.Features))
+	assert.Equal(t, 0, resp.Features[0].Feature)
+	assert.Equal(t, "park", resp.Features[0].ID)
+	assert.NotEmpty(t, resp.Features[0].Tokens)
+	assert.Equal(t, 2, resp.Features[1].Feature)
+	assert.Equal(t, 7.0, resp.Features[1].ID)
+	assert.Equal(t, []string{s2.CellIDFromLatLng(s2.LatLngFromDegrees(20, 20)).Parent(6).ToToken()}, resp.Features[1].Tokens)
+}
+
+func TestCoverGroupByLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("group_by_level", "true")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens  string              `json:"cell_tokens"`
+		ByLevel map[string][]string `json:"cells_by_level"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, len(resp.ByLevel) > 1)
+	n := 0
+	for level, tokens := range resp.ByLevel {
+		for _, tk := range tokens {
+			assert.Equal(t, level, strconv.Itoa(s2.CellIDFromToken(tk).Level()))
+			assert.Contains(t, resp.Tokens, tk)
+		}
+		n += len(tokens)
+	}
+	assert.Equal(t, len(strings.Split(resp.Tokens, ",")), n)
+
+	data.Del("group_by_level")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.NotContains(t, w.Body.String(), "cells_by_level")
+}
+
+func TestCoverBufferCells(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	cover := func(buffer string) (int, []string) {
+		data := url.Values{}
+		data.Set("uniform_level", "8")
+		data.Set("buffer_cells", buffer)
+		data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+
+		var resp struct {
+			Tokens string `json:"cell_tokens"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Result().StatusCode, strings.Split(resp.Tokens, ",")
+	}
+
+	code, plain := cover("")
+	assert.Equal(t, 200, code)
+	code, buffered := cover("1")
+	assert.Equal(t, 200, code)
+	assert.True(t, len(buffered) > len(plain))
+	var cu s2.CellUnion
+	for _, tk := range plain {
+		cu = append(cu, s2.CellIDFromToken(tk))
+	}
+	cu.Normalize()
+	for _, tk := range buffered {
+		id := s2.CellIDFromToken(tk)
+		if cu.ContainsCellID(id) {
+			continue
+		}
+		adjacent := false
+		for _, nb := range id.EdgeNeighbors() {
+			adjacent = adjacent || cu.ContainsCellID(nb)
+		}
+		assert.True(t, adjacent, tk)
+	}
+
+	code, _ = cover("11")
+	assert.Equal(t, 400, code)
+	code, _ = cover("-1")
+	assert.Equal(t, 400, code)
+}
+
+func TestCoverSmooth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	cover := func(smooth string) (int, string) {
+		data := url.Values{}
+		data.Set("max_level_geojson", "12")
+		data.Set("smooth", smooth)
+		data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+
+		var resp struct {
+			Tokens string `json:"cell_tokens"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Result().StatusCode, resp.Tokens
+	}
+
+	code, plain := cover("")
+	assert.Equal(t, 200, code)
+	code, smoothed := cover("2")
+	assert.Equal(t, 200, code)
+	assert.NotEqual(t, plain, smoothed)
+	// the cut off corner of the square is only in the plain covering
+	corner := s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.001, 0.001)).Parent(12)
+	covered := false
+	for _, tk := range strings.Split(plain, ",") {
+		covered = covered || s2.CellIDFromToken(tk).Contains(corner)
+	}
+	assert.True(t, covered)
+	for _, tk := range strings.Split(smoothed, ",") {
+		assert.False(t, s2.CellIDFromToken(tk).Contains(corner), tk)
+	}
+
+	code, _ = cover("6")
+	assert.Equal(t, 400, code)
+	code, _ = cover("x")
+	assert.Equal(t, 400, code)
+}
+
+func TestCoverHull(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	cover := func(hull string) []string {
+		data := url.Values{}
+		data.Set("max_level_geojson", "10")
+		data.Set("hull", hull)
+		// a square with a deep notch at the top
+		data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[2,0],[2,2],[1,0.2],[0,2],[0,0]]]}}]}`)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+
+		var resp struct {
+			Tokens string `json:"cell_tokens"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return strings.Split(resp.Tokens, ",")
+	}
+	covers := func(tokens []string, lat, lng float64) bool {
+		id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
+		for _, tk := range tokens {
+			if s2.CellIDFromToken(tk).Contains(id) {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.False(t, covers(cover(""), 1.5, 1))
+	assert.True(t, covers(cover("true"), 1.5, 1))
+}
+
+func TestCoverAdjacency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("uniform_level", "8")
+	data.Set("adjacency", "true")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens    string              `json:"cell_tokens"`
+		Adjacency map[string][]string `json:"adjacency"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	tokens := strings.Split(resp.Tokens, ",")
+	assert.Equal(t, len(tokens), len(resp.Adjacency))
+	for _, tk := range tokens {
+		neighbors := resp.Adjacency[tk]
+		// a grid of cells of one level, each one has 2 to 4 neighbors
+		assert.True(t, len(neighbors) >= 2 && len(neighbors) <= 4, tk)
+		for _, nb := range neighbors {
+			assert.Contains(t, s2.CellIDFromToken(tk).EdgeNeighbors(), s2.CellIDFromToken(nb))
+		}
+	}
+}
+
+func TestCoverVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+	cover := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	plain := cover()
+	assert.NotContains(t, plain, "verify_dropped")
+	data.Set("verify", "true")
+	verified := cover()
+	assert.Equal(t, 0.0, verified["verify_dropped"])
+	assert.Equal(t, plain["cell_tokens"], verified["cell_tokens"])
+}
+
+func TestCoverCoordOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "8")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[10,40],[11,40],[11,41],[10,41],[10,40]]]}}]}`)
+
+	cover := func(order string) (int, [][][]float64) {
+		data.Set("coord_order", order)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		var resp struct {
+			Cells [][][]float64 `json:"cells"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Result().StatusCode, resp.Cells
+	}
+
+	status, latLng := cover("")
+	assert.Equal(t, 200, status)
+	assert.NotEmpty(t, latLng)
+	assert.True(t, latLng[0][0][0] > 30)
+
+	status, legacy := cover("latlng")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, latLng, legacy)
+
+	status, lngLat := cover("lnglat")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, len(latLng), len(lngLat))
+	for i, cell := range lngLat {
+		for j, v := range cell {
+			assert.Equal(t, []float64{latLng[i][j][1], latLng[i][j][0]}, v)
+		}
+	}
+
+	status, _ = cover("xy")
+	assert.Equal(t, 400, status)
+
+	data.Set("output_srid", "3857")
+	status, yx := cover("")
+	assert.Equal(t, 200, status)
+	status, xy := cover("lnglat")
+	assert.Equal(t, 200, status)
+	for i, cell := range xy {
+		for j, v := range cell {
+			assert.Equal(t, []float64{yx[i][j][1], yx[i][j][0]}, v)
+			// the cells around 10 to 11 degrees east and 40 to 41 north
+			assert.True(t, v[0] > 1001875 && v[0] < 1335833)
+			assert.True(t, v[1] > 4721671 && v[1] < 5160979)
+		}
+	}
+
+	data.Set("output_srid", "27700")
+	status, _ = cover("")
+	assert.Equal(t, 400, status)
+}
+
+func TestCoverDiff(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("level_a", "6")
+	data.Set("level_b", "10")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover_diff", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Removed)
+
+	data.Set("level_b", "6")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_diff", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Added)
+	assert.Empty(t, resp.Removed)
+
+	data.Del("level_a")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_diff", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestDiffTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("tokens_a", "04,0c")
+	data.Set("tokens_b", "0c,14")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/diff_tokens", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"added\":[\"14\"],\"changed\":[\"04\",\"14\"],\"removed\":[\"04\"]}\n", w.Body.String())
+
+	data.Set("tokens_b", "0c,zz")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/diff_tokens", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoverLine(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "8")
+	data.Set("encoded_polyline", "_p~iF~ps|U_ulLnnqC_mqNvxq`@")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover_line", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var cu s2.CellUnion
+	for _, token := range strings.Split(resp.Tokens, ",") {
+		cu = append(cu, s2.CellIDFromToken(token))
+	}
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(40.7, -120.95))))
+
+	data.Set("encoded_polyline", "_p~iF~ps|U_ulL")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_line", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"encoded_polyline: truncated polyline\"}\n", w.Body.String())
+
+	data.Del("encoded_polyline")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_line", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[0,0]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_line", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
 }
 
-func TestCheckIntersection(t *testing.T) {
+func TestCellsAlongLine(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	r := server.NewRouter(root)
 
+	data := url.Values{}
+	data.Set("level", "8")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[1,0.01],[0.01,0.01]]}}]}`)
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/check_intersection", nil)
+	req, _ := http.NewRequest("POST", "/cells_along_line", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Level int `json:"level"`
+		Lines []struct {
+			Tokens string        `json:"cell_tokens"`
+			Cells  [][][]float64 `json:"cells"`
+		} `json:"lines"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 8, resp.Level)
+	assert.Equal(t, 1, len(resp.Lines))
+	// westwards, unlike the sorted covering
+	assert.Equal(t, "1000b,10009,10007,10001", resp.Lines[0].Tokens)
+	assert.Equal(t, 4, len(resp.Lines[0].Cells))
+
+	for _, level := range []string{"31", "x"} {
+		data.Set("level", level)
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/cells_along_line", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Result().StatusCode)
+	}
+
+	data.Set("level", "8")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[0,0]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cells_along_line", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	// a long line at the leaf level is rejected
+	data.Set("level", "30")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[0,0],[10,0]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cells_along_line", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "too many cells")
+
+	data.Set("level", "8")
+	data.Del("geojson")
+	data.Set("encoded_polyline", "_p~iF ~ps|U")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cells_along_line", strings.NewReader(data.Encode()))
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	r.ServeHTTP(w, req)
 	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"encoded_polyline: invalid polyline character ' ' at 5\"}\n", w.Body.String())
+}
+
+func TestCirclePolygon(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
 
 	data := url.Values{}
-	data.Set("radius", "1000")
-	data.Set("max_level_circle", "12")
 	data.Set("lat", "35.5666")
 	data.Set("lng", "23.4444")
-	data.Set("tokens", "48761ac,48761b4")
+	data.Set("radius", "1000")
+	data.Set("segments", "16")
+	circle := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/circle_polygon", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := circle()
+	assert.Equal(t, 200, w.Result().StatusCode)
+	var resp struct {
+		Polygon geojson.Geometry `json:"polygon"`
+		Tokens  *string          `json:"cell_tokens"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Polygon.IsPolygon())
+	assert.Equal(t, 17, len(resp.Polygon.Polygon[0]))
+	assert.Nil(t, resp.Tokens)
+
+	data.Set("cover", "true")
+	data.Set("max_level_geojson", "14")
+	w = circle()
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotNil(t, resp.Tokens)
+	center := s2.CellIDFromLatLng(s2.LatLngFromDegrees(35.5666, 23.4444))
+	covered := false
+	for _, tk := range strings.Split(*resp.Tokens, ",") {
+		covered = covered || s2.CellIDFromToken(tk).Contains(center)
+	}
+	assert.True(t, covered)
+
+	for field, v := range map[string]string{"radius": "0", "segments": "2", "lat": "x"} {
+		data.Set(field, v)
+		w = circle()
+		assert.Equal(t, 400, w.Result().StatusCode, field)
+		data.Set("radius", "1000")
+		data.Set("segments", "16")
+		data.Set("lat", "35.5666")
+	}
+}
+
+func TestCoverAnnulus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("lat", "40")
+	data.Set("lng", "10")
+	data.Set("inner_radius", "1000")
+	data.Set("outer_radius", "5000")
+	data.Set("level", "14")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover_annulus", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		Tokens string        `json:"cell_tokens"`
+		Cells  [][][]float64 `json:"cells"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var cu s2.CellUnion
+	for _, token := range strings.Split(resp.Tokens, ",") {
+		cu = append(cu, s2.CellIDFromToken(token))
+	}
+	assert.Equal(t, len(cu), len(resp.Cells))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(40, 10))))
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(40.027, 10))))
+
+	for field, v := range map[string]string{"inner_radius": "6000", "outer_radius": "x", "lat": ""} {
+		bad := url.Values{}
+		for k := range data {
+			bad.Set(k, data.Get(k))
+		}
+		bad.Set(field, v)
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/cover_annulus", strings.NewReader(bad.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Result().StatusCode)
+	}
+}
+
+func TestCoverMVT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("z", "1")
+	data.Set("x", "1")
+	data.Set("y", "0")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover_mvt", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "application/vnd.mapbox-vector-tile", w.Header().Get("Content-Type"))
+	assert.True(t, w.Body.Len() > 0)
+
+	data.Set("x", "2")
 	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("POST", "/check_intersection", strings.NewReader(data.Encode()))
+	req, _ = http.NewRequest("POST", "/cover_mvt", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data.Del("z")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover_mvt", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestTokensToPolygon(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("tokens", "14,b4")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tokens_to_polygon", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	f, err := geojson.UnmarshalFeature(w.Body.Bytes())
+	assert.NoError(t, err)
+	assert.True(t, f.Geometry.IsMultiPolygon())
+	assert.Equal(t, 2, len(f.Geometry.MultiPolygon))
+
+	data.Set("tokens", "14d607,zz")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/tokens_to_polygon", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoveringTiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("zoom", "1")
+	data.Set("tokens", "1,0ff")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/covering_tiles", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `{"tiles":[{"z":1,"x":0,"y":0},{"z":1,"x":0,"y":1},{"z":1,"x":1,"y":0},{"z":1,"x":1,"y":1}]}`+"\n", w.Body.String())
+
+	data.Set("zoom", "12")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/covering_tiles", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	// past the deepest web map zoom
+	data.Set("zoom", "23")
+	data.Set("tokens", s2.CellIDFromLatLng(s2.LatLngFromDegrees(10, 10)).Parent(20).ToToken())
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/covering_tiles", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, `{"error":"zoom must be between 0 and 22"}`+"\n", w.Body.String())
+
+	// a polar cell spans all longitudes and overlaps whole rows of tiles
+	data.Set("zoom", "11")
+	data.Set("tokens", s2.CellIDFromLatLng(s2.LatLngFromDegrees(89.999, 10)).Parent(4).ToToken())
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/covering_tiles", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "too many tiles")
+
+	data.Del("zoom")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/covering_tiles", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestInspect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[0,1],[1,1],[1,0],[0,0]]]}}]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/inspect", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	var resp struct {
+		Rings []map[string]interface{} `json:"rings"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, len(resp.Rings))
+	ring := resp.Rings[0]
+	assert.InDelta(t, 111195, ring["longest_edge_meters"], 1)
+	assert.InDelta(t, 111178, ring["min_width_meters"], 1)
+	delete(ring, "longest_edge_meters")
+	delete(ring, "min_width_meters")
+	assert.Equal(t, map[string]interface{}{"feature": 0.0, "ring": 0.0, "vertices": 4.0, "closed": true, "winding": "cw", "valid": true}, ring)
+
+	data.Set("geojson", "{")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/inspect", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestMinimalEnclosingCell(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	post := func(geojson string) *httptest.ResponseRecorder {
+		data := url.Values{}
+		data.Set("geojson", geojson)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/minimal_enclosing_cell", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[2,1]}}]}`)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	var resp struct {
+		Token   string           `json:"token"`
+		Level   int              `json:"level"`
+		Polygon geojson.Geometry `json:"polygon"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, s2.CellIDFromLatLng(s2.LatLngFromDegrees(1, 2)).ToToken(), resp.Token)
+	assert.Equal(t, 30, resp.Level)
+	assert.True(t, resp.Polygon.IsPolygon())
+
+	w = post(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[2,1]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[2.1,1],[2.2,1],[2.2,1.1],[2.1,1]]]}}]}`)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Level > 4 && resp.Level < 30)
+	assert.True(t, s2.CellIDFromToken(resp.Token).Contains(s2.CellIDFromLatLng(s2.LatLngFromDegrees(1.1, 2.2))))
+
+	w = post(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[2,1]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[180,1]}}]}`)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	w = post(`{"type":"FeatureCollection","features":[]}`)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestTriangulate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,1],[0,0]]]}}]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/triangulate", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, `{"polygons":[{"feature":0,"polygon":0,"vertices":[[0,0],[1,0],[0,1]],"triangles":[[2,0,1]]}]}`+"\n", w.Body.String())
+
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,1],[0,0]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/triangulate", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+}
+
+func TestCoveringContainsPoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	post := func(data url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/covering_contains_point", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	data := url.Values{}
+	data.Set("tokens", "14d607,14d60c")
+	data.Set("lat", "38.34")
+	data.Set("lng", "34.34")
+
+	var resp struct {
+		Contains bool `json:"contains"`
+	}
+	w := post(data)
+	assert.Equal(t, 200, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Contains)
+
+	data.Set("lat", "-38.34")
+	w = post(data)
+	assert.Equal(t, 200, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Contains)
+
+	data.Set("tokens", "14d607,zz")
+	w = post(data)
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestCoverPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "6")
+	data.Set("paths", "true")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[34.34,38.34]}}]}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var resp struct {
+		Paths []string `json:"cell_paths"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, len(resp.Paths))
+	assert.Regexp(t, "^[0-5]/[0-3]{6}$", resp.Paths[0])
+}
+
+func TestCoverMinCellArea(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "14")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+
+	var resp struct {
+		Stats struct {
+			MaxLevel  int `json:"max_level"`
+			CellCount int `json:"cell_count"`
+		} `json:"stats"`
+	}
+	cover := func() {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+
+	cover()
+	cells, maxLevel := resp.Stats.CellCount, resp.Stats.MaxLevel
+	data.Set("min_cell_area_sqm", "100000000")
+	cover()
+	assert.True(t, resp.Stats.CellCount < cells)
+	assert.True(t, resp.Stats.MaxLevel < maxLevel)
+}
+
+func TestFaces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	var resp struct {
+		Faces    []int    `json:"faces"`
+		Warnings []string `json:"warnings"`
+	}
+	faces := func(geoJSON string) {
+		data := url.Values{}
+		data.Set("geojson", geoJSON)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/faces", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		resp.Warnings = nil
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+
+	faces(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+	assert.Equal(t, []int{0}, resp.Faces)
+	assert.Empty(t, resp.Warnings)
+
+	// spanning the boundary of face 0 and 1 at 45 degrees east
+	faces(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[44,0],[46,0],[46,1],[44,1],[44,0]]]}}]}`)
+	assert.Equal(t, []int{0, 1}, resp.Faces)
+	assert.Equal(t, []string{"geometries span 2 faces"}, resp.Warnings)
+}
+
+func TestCoverTokensOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("tokens_only", "true")
+	data.Set("geojson", string(validJSON))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp["cell_tokens"])
+	assert.NotContains(t, resp, "cells")
+}
+
+func TestCoverWindow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "8")
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","bbox":[20,20,21,21],"properties":{},"geometry":{"type":"Polygon","coordinates":[[[20,20],[21,20],[21,21],[20,21],[20,20]]]}}]}`)
+
+	var resp struct {
+		Tokens string `json:"cell_tokens"`
+	}
+	cover := func(window string) int {
+		data.Set("window", window)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		if w.Code != 200 {
+			return w.Code
+		}
+		resp.Tokens = ""
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code
+	}
+
+	assert.Equal(t, 200, cover(""))
+	all := resp.Tokens
+	assert.Equal(t, 200, cover("-1,-1,2,2"))
+	assert.NotEmpty(t, resp.Tokens)
+	assert.True(t, len(resp.Tokens) < len(all))
+	assert.Equal(t, 200, cover("50,50,51,51"))
+	assert.Empty(t, resp.Tokens)
+
+	assert.Equal(t, 400, cover("1,2,3"))
+	assert.Equal(t, 400, cover("0,2,1,1"))
+
+	// a point without coordinates has an empty bbox outside of any window
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}]}`)
+	assert.Equal(t, 200, cover("-1,-1,2,2"))
+	assert.NotEmpty(t, resp.Tokens)
+}
+
+func TestCoverConfiguredDefaultLevels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	defer func(min, max int) { controllers.DefaultMinLevel, controllers.DefaultMaxLevel = min, max }(controllers.DefaultMinLevel, controllers.DefaultMaxLevel)
+	controllers.DefaultMinLevel, controllers.DefaultMaxLevel = 2, 10
+
+	r := server.NewRouter(root)
+
+	data := url.Values{}
+	data.Set("geojson", string(validJSON))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	r.ServeHTTP(w, req)
 	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var resp struct {
+		MaxLevel int `json:"max_level_geojson"`
+		Stats    struct {
+			MinLevel int `json:"min_level"`
+			MaxLevel int `json:"max_level"`
+		} `json:"stats"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 10, resp.MaxLevel)
+	assert.True(t, resp.Stats.MinLevel >= 2)
+	assert.True(t, resp.Stats.MaxLevel <= 10)
+}
+
+func TestCoverSnapDecimals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	// the ring collapses to a single vertex once snapped to 7 decimals
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},
+		"geometry":{"type":"Polygon","coordinates":[[[0,0],[0.00000001,0],[0,0.00000001],[0,0]]]}}]}`)
+	cover := func() []string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Result().StatusCode)
+
+		var resp struct {
+			Warnings []string `json:"warnings"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Warnings
+	}
+	assert.Empty(t, cover())
+
+	data.Set("snap_decimals", "7")
+	assert.Equal(t, []string{"feature 0: degenerate ring: less than 3 distinct vertices, ring skipped"}, cover())
+
+	data.Set("snap_decimals", "16")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+	assert.Equal(t, `{"error":"snap_decimals must be between 0 and 15"}`+"\n", w.Body.String())
 }
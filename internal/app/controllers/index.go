@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pantrif/s2-geojson/pkg/geo"
+	"strconv"
+	"sync"
+)
+
+const (
+	// maxIndexes is the number of registered indexes kept, the oldest index is dropped to register a new one
+	maxIndexes = 100
+	// maxIndexFeatures is the max number of features of an index
+	maxIndexFeatures = 10000
+	// maxIndexVertices is the max number of polygon vertices of an index
+	maxIndexVertices = 200000
+	// maxIndexedVertices is the max total number of polygon vertices of the registered indexes, the oldest
+	// indexes are dropped to register a new one over it
+	maxIndexedVertices = 2000000
+)
+
+// indexRegistry keeps the registered feature indexes by id in registration order with their vertex counts
+type indexRegistry struct {
+	mu       sync.Mutex
+	indexes  map[string]*geo.FeatureIndex
+	vertices map[string]int
+	total    int
+	order    []string
+}
+
+var indexes = &indexRegistry{indexes: make(map[string]*geo.FeatureIndex), vertices: make(map[string]int)}
+
+// add registers the index of the number of polygon vertices under a new random id
+func (r *indexRegistry) add(fi *geo.FeatureIndex, vertices int) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.order) > 0 && (len(r.order) >= maxIndexes || r.total+vertices > maxIndexedVertices) {
+		oldest := r.order[0]
+		r.total -= r.vertices[oldest]
+		delete(r.indexes, oldest)
+		delete(r.vertices, oldest)
+		r.order = r.order[1:]
+	}
+	r.indexes[id] = fi
+	r.vertices[id] = vertices
+	r.total += vertices
+	r.order = append(r.order, id)
+	return id, nil
+}
+
+func (r *indexRegistry) get(id string) (*geo.FeatureIndex, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fi, ok := r.indexes[id]
+	return fi, ok
+}
+
+// RegisterIndex indexes the polygons of the geojson features for repeated queries through QueryIndex. Indexes
+// of more than maxIndexFeatures features or maxIndexVertices polygon vertices are rejected with 413, the
+// registered indexes are kept up to maxIndexes of them and maxIndexedVertices vertices in total.
+func (u GeometryController) RegisterIndex(c *gin.Context) {
+	fs, err := decodeFeatures(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(fs) > maxIndexFeatures {
+		c.JSON(413, gin.H{
+			"error": fmt.Sprintf("index too large: %d features, at most %d", len(fs), maxIndexFeatures),
+		})
+		return
+	}
+	vertices := 0
+	for _, f := range fs {
+		for _, ring := range geo.PolygonRings(f.Geometry) {
+			vertices += len(ring)
+		}
+	}
+	if vertices > maxIndexVertices {
+		c.JSON(413, gin.H{
+			"error": fmt.Sprintf("index too large: %d polygon vertices, at most %d", vertices, maxIndexVertices),
+		})
+		return
+	}
+
+	id, err := indexes.add(geo.NewFeatureIndex(fs), vertices)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"index_id": id,
+		"features": len(fs),
+	})
+}
+
+// QueryIndex returns the ids and properties of the features of a registered index containing the point,
+// or intersecting the circle around it when a radius in meters is given
+func (u GeometryController) QueryIndex(c *gin.Context) {
+	fi, ok := indexes.get(c.PostForm("index_id"))
+	if !ok {
+		c.JSON(404, gin.H{
+			"error": "unknown index_id",
+		})
+		return
+	}
+	lat, err := strconv.ParseFloat(c.PostForm("lat"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.PostForm("lng"), 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	radius, err := floatParam(c, "radius")
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	p := geo.Point{Lat: lat, Lng: lng}
+	positions := fi.ContainsPoint(p)
+	if radius > 0 {
+		positions = fi.IntersectsCircle(p, radius)
+	}
+
+	matches := []gin.H{}
+	for _, i := range positions {
+		matches = append(matches, gin.H{
+			"id":         fi.Features[i].ID,
+			"properties": fi.Features[i].Properties,
+		})
+	}
+
+	c.JSON(200, gin.H{
+		"features": matches,
+	})
+}
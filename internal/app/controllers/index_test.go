@@ -0,0 +1,103 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pantrif/s2-geojson/internal/app/server"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/index", nil)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Result().StatusCode)
+
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"a","properties":{"name":"A"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","id":"b","properties":{"name":"B"},"geometry":{"type":"Polygon","coordinates":[[[5,5],[6,5],[6,6],[5,6],[5,5]]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/index", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+
+	var registered struct {
+		IndexID  string `json:"index_id"`
+		Features int    `json:"features"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &registered))
+	assert.Equal(t, 2, registered.Features)
+	assert.Len(t, registered.IndexID, 32)
+
+	data = url.Values{}
+	data.Set("index_id", "missing")
+	data.Set("lat", "0.5")
+	data.Set("lng", "0.5")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/index_query", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Result().StatusCode)
+
+	data.Set("index_id", registered.IndexID)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/index_query", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"features\":[{\"id\":\"a\",\"properties\":{\"name\":\"A\"}}]}\n", w.Body.String())
+
+	// about 55km from the corner of the second polygon
+	data.Set("lat", "4.5")
+	data.Set("lng", "4.9")
+	data.Set("radius", "60000")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/index_query", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, "{\"features\":[{\"id\":\"b\",\"properties\":{\"name\":\"B\"}}]}\n", w.Body.String())
+}
+
+func TestIndexTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := server.NewRouter(root)
+
+	features := strings.Repeat(`{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[0,0]}},`, 10001)
+	data := url.Values{}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[`+strings.TrimSuffix(features, ",")+`]}`)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/index", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 413, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"index too large: 10001 features, at most 10000\"}\n", w.Body.String())
+
+	var ring []string
+	for i := 0; i <= 200000; i++ {
+		ring = append(ring, fmt.Sprintf("[%g,%g]", math.Cos(float64(i)), math.Sin(float64(i))))
+	}
+	data.Set("geojson", `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},
+		"geometry":{"type":"Polygon","coordinates":[[`+strings.Join(ring, ",")+`]]}}]}`)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/index", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 413, w.Result().StatusCode)
+	assert.Equal(t, "{\"error\":\"index too large: 200001 polygon vertices, at most 200000\"}\n", w.Body.String())
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pantrif/s2-geojson/pkg/logger"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxBodyBytes is the maximum size in bytes of the request bodies of the covering endpoints and of
+// any decompressed request body
+var MaxBodyBytes int64 = 32 << 20
+
+// RateLimit is the number of requests per second each client IP may send to the heavy covering endpoints,
+// non positive disables rate limiting
+var RateLimit float64
+
+// RateBurst is the number of requests a client IP may send at once before being rate limited
+var RateBurst = 10
+
+// maxRateBuckets is the number of client IPs tracked before the idle ones are dropped
+const maxRateBuckets = 10000
+
+// limitBody rejects request bodies larger than MaxBodyBytes with 413
+func limitBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes))
+		if err != nil {
+			status := 400
+			if int64(len(body)) >= MaxBodyBytes {
+				status = 413
+			}
+			c.AbortWithStatusJSON(status, gin.H{
+				"error": fmt.Sprintf("invalid body: %v", err),
+			})
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// gunzip transparently decompresses request bodies sent with the gzip content encoding
+func gunzip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.EqualFold(c.GetHeader("Content-Encoding"), "gzip") || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		body, err := decompress(c)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{
+				"error": fmt.Sprintf("invalid gzip body: %v", err),
+			})
+			return
+		}
+		if int64(len(body)) > MaxBodyBytes {
+			c.AbortWithStatusJSON(413, gin.H{
+				"error": fmt.Sprintf("decompressed body exceeds %d bytes", MaxBodyBytes),
+			})
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		c.Next()
+	}
+}
+
+func decompress(c *gin.Context) ([]byte, error) {
+	zr, err := gzip.NewReader(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	// read one byte past the limit to detect larger bodies
+	return ioutil.ReadAll(io.LimitReader(zr, MaxBodyBytes+1))
+}
+
+// recoverJSON recovers from panics of the handlers logging the stack and responding with a JSON 500 error
+func recoverJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				logger.Error("panic", "error", err, "stack", string(debug.Stack()))
+				c.AbortWithStatusJSON(500, gin.H{
+					"error": "internal error",
+				})
+			}
+		}()
+		c.Next()
+	}
+}
+
+// bucket is the token bucket of a client IP
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the requests of each client IP with a token bucket refilled at rate tokens per second
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// allow takes a token of the bucket of the ip, returning the wait for the next token when it is empty
+func (l *rateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.buckets) >= maxRateBuckets {
+		// drop the buckets refilled to the burst, they are the same as new ones
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+	}
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// rateLimit rejects the requests of client IPs exceeding RateLimit with 429 and a Retry-After header in
+// seconds. The client IP is taken from the X-Forwarded-For header when present, which only a proxy in
+// front of the service can be trusted to set.
+func rateLimit() gin.HandlerFunc {
+	if RateLimit <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	l := newRateLimiter(RateLimit, RateBurst)
+	return func(c *gin.Context) {
+		if ok, wait := l.allow(c.ClientIP()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(429, gin.H{
+				"error": "rate limit exceeded",
+			})
+			return
+		}
+		c.Next()
+	}
+}
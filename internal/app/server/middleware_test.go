@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGunzip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(root)
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	data.Set("min_level_geojson", "1")
+	data.Set("geojson", string(validJSON))
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, err := zw.Write([]byte(data.Encode()))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", &body)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "cell_tokens")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid gzip body")
+}
+
+func TestLimitBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(root)
+
+	defer func(max int64) { MaxBodyBytes = max }(MaxBodyBytes)
+	MaxBodyBytes = 1024
+
+	data := url.Values{}
+	data.Set("max_level_geojson", "10")
+	// whitespace padding compresses well below the limit
+	data.Set("geojson", string(validJSON)+strings.Repeat(" ", 2048))
+	assert.True(t, len(data.Encode()) > 1024)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 413, w.Code)
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, err := zw.Write([]byte(data.Encode()))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	assert.True(t, body.Len() < 1024)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", &body)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 413, w.Code)
+
+	MaxBodyBytes = 1 << 20
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cover", strings.NewReader(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestRecoverJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(root)
+	router.GET("/panic", func(c *gin.Context) {
+		var loops []int
+		c.JSON(200, loops[1])
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, "{\"error\":\"internal error\"}\n", w.Body.String())
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow("a")
+		assert.True(t, ok)
+	}
+	ok, wait := l.allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	ok, _ = l.allow("b")
+	assert.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.allow("a")
+	assert.True(t, ok)
+	ok, _ = l.allow("a")
+	assert.False(t, ok)
+
+	// the refill is capped at the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		ok, _ = l.allow("a")
+		assert.True(t, ok)
+	}
+	ok, _ = l.allow("a")
+	assert.False(t, ok)
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	defer func(rate float64, burst int) { RateLimit, RateBurst = rate, burst }(RateLimit, RateBurst)
+	RateLimit, RateBurst = 0.1, 2
+	router := NewRouter(root)
+
+	post := func(path, ip string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, nil)
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, 400, post("/cover", "10.0.0.1").Code)
+	assert.Equal(t, 400, post("/check_intersection", "10.0.0.1").Code)
+	w := post("/cover", "10.0.0.1")
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Equal(t, "{\"error\":\"rate limit exceeded\"}\n", w.Body.String())
+	assert.Equal(t, 429, post("/batch_cover", "10.0.0.1").Code)
+
+	assert.Equal(t, 400, post("/cover", "10.0.0.2").Code)
+	// the limit is shared by all the POST routes
+	assert.Equal(t, 429, post("/inspect", "10.0.0.1").Code)
+
+	RateLimit = 0
+	router = NewRouter(root)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, 400, post("/cover", "10.0.0.1").Code)
+	}
+}
@@ -11,7 +11,8 @@ func NewRouter(root string) *gin.Engine {
 	health := new(controllers.HealthController)
 	p := new(controllers.GeometryController)
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Logger(), recoverJSON(), gunzip())
 	r.GET("/health", health.Status)
 	r.LoadHTMLGlob(root + "/*.html")
 	r.Static("/js", root+"/js")
@@ -20,8 +21,39 @@ func NewRouter(root string) *gin.Engine {
 		c.HTML(http.StatusOK, "index.html", nil)
 	})
 
-	r.POST("/cover", p.Cover)
-	r.POST("/check_intersection", p.CheckIntersection)
+	limit, rate := limitBody(), rateLimit()
+	r.POST("/cover", rate, limit, p.Cover)
+	r.POST("/batch_cover", rate, limit, p.BatchCover)
+	r.POST("/cover_bbox", rate, limit, p.CoverBBox)
+	r.POST("/cover_diff", rate, limit, p.CoverDiff)
+	r.POST("/cover_overlap", rate, limit, p.CoverOverlap)
+	r.POST("/diff_tokens", rate, limit, p.DiffTokens)
+	r.POST("/cover_mvt", rate, limit, p.CoverMVT)
+	r.POST("/cover_line", rate, limit, p.CoverLine)
+	r.POST("/cells_along_line", rate, limit, p.CellsAlongLine)
+	r.POST("/circle_polygon", rate, limit, p.CirclePolygon)
+	r.POST("/cover_annulus", rate, limit, p.CoverAnnulus)
+	r.POST("/faces", rate, limit, p.Faces)
+	r.POST("/inspect", rate, limit, p.Inspect)
+	r.POST("/triangulate", rate, limit, p.Triangulate)
+	r.POST("/check_intersection", rate, limit, p.CheckIntersection)
+	r.POST("/check_points", rate, limit, p.CheckPoints)
+	r.POST("/contains_all_points", rate, limit, p.ContainsAllPoints)
+	r.POST("/snap_to_grid", rate, limit, p.SnapToGrid)
+	r.POST("/locate_point", rate, limit, p.LocatePoint)
+	r.POST("/nearest_feature", rate, limit, p.NearestFeature)
+	r.POST("/k_nearest_features", rate, limit, p.KNearestFeatures)
+	r.POST("/contains_polygon", rate, limit, p.ContainsPolygon)
+	r.POST("/intersection_matrix", rate, limit, p.IntersectionMatrix)
+	r.POST("/covering_contains_point", rate, limit, p.CoveringContainsPoint)
+	r.POST("/tokens_to_polygon", rate, limit, p.TokensToPolygon)
+	r.POST("/covering_tiles", rate, limit, p.CoveringTiles)
+	r.POST("/minimal_enclosing_cell", rate, limit, p.MinimalEnclosingCell)
+	r.GET("/hover_cell", p.HoverCell)
+	r.GET("/cell_info", p.CellInfo)
+	r.GET("/level_for_cell_size", p.LevelForCellSize)
+	r.POST("/index", rate, limit, p.RegisterIndex)
+	r.POST("/index_query", rate, limit, p.QueryIndex)
 
 	return r
 }
@@ -5,6 +5,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -57,3 +59,48 @@ func TestRouter(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	assert.Equal(t, "{\"status\":\"ok\"}\n", w.Body.String())
 }
+
+func TestRouterLimitsBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(root)
+
+	defer func(max int64) { MaxBodyBytes = max }(MaxBodyBytes)
+	MaxBodyBytes = 1024
+
+	// every route reading a body rejects oversized ones before decoding them
+	for _, route := range router.Routes() {
+		if route.Method != "POST" {
+			continue
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", route.Path, strings.NewReader("geojson="+strings.Repeat("x", 2048)))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 413, w.Code, route.Path)
+	}
+}
+
+func TestRouterRateLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	defer func(rate float64, burst int) { RateLimit, RateBurst = rate, burst }(RateLimit, RateBurst)
+	RateLimit, RateBurst = 0.001, 1
+	router := NewRouter(root)
+
+	// every route reading a body is rate limited, here each route is requested by its own client
+	for n, route := range router.Routes() {
+		if route.Method != "POST" {
+			continue
+		}
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", route.Path, strings.NewReader("geojson="+string(validJSON)))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			req.RemoteAddr = "10.0.0." + strconv.Itoa(n) + ":1234"
+			router.ServeHTTP(w, req)
+			if i == 1 {
+				assert.Equal(t, 429, w.Code, route.Path)
+			}
+		}
+	}
+}
@@ -1,11 +1,53 @@
 package server
 
-// Init initializes the router
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout is how long the server waits for the in-flight requests to finish on SIGTERM or interrupt
+// before closing their connections
+var ShutdownTimeout = 30 * time.Second
+
+// Init initializes the router and serves it on the address, or on the PORT environment variable or :8080
+// when omitted, until SIGTERM or interrupt shuts it down gracefully
 func Init(webPath string, addr ...string) error {
-	r := NewRouter(webPath)
-	err := r.Run(addr...)
+	address := ":8080"
+	if len(addr) > 0 {
+		address = addr[0]
+	} else if port := os.Getenv("PORT"); port != "" {
+		address = ":" + port
+	}
+	l, err := net.Listen("tcp", address)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(stop)
+	return serve(&http.Server{Handler: NewRouter(webPath)}, l, stop)
+}
+
+// serve serves on the listener until stop receives, then stops accepting connections and waits up to
+// ShutdownTimeout for the in-flight requests to finish
+func serve(srv *http.Server, l net.Listener, stop <-chan os.Signal) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(l)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-stop:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
 }
@@ -3,7 +3,12 @@ package server
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestServer(t *testing.T) {
@@ -11,3 +16,39 @@ func TestServer(t *testing.T) {
 	err := Init(root, "3333")
 	assert.Error(t, err)
 }
+
+func TestServeShutdown(t *testing.T) {
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(200)
+	})}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	stop := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(srv, l, stop)
+	}()
+
+	// the slow in-flight request completes although the shutdown starts while it is served
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+	stop <- syscall.SIGTERM
+	assert.NoError(t, <-done)
+	assert.Equal(t, 200, <-status)
+
+	_, err = http.Get("http://" + l.Addr().String())
+	assert.Error(t, err)
+}
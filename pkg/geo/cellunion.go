@@ -0,0 +1,407 @@
+package geo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidCellUnionEncoding is returned when decoding bytes not produced by MarshalCellUnion
+var ErrInvalidCellUnionEncoding = errors.New("invalid cell union encoding")
+
+// vertexKey quantizes a point so that cell vertices computed from different faces match
+type vertexKey [3]int64
+
+func keyOf(p s2.Point) vertexKey {
+	return vertexKey{int64(math.Round(p.X * 1e12)), int64(math.Round(p.Y * 1e12)), int64(math.Round(p.Z * 1e12))}
+}
+
+type cellEdge struct {
+	a, b s2.Point
+}
+
+// CellUnionToPolygon merges the cells of the union to a polygon whose loops follow the outer cell edges
+func CellUnionToPolygon(cu s2.CellUnion) *s2.Polygon {
+	cu = s2.CellUnionFromUnion(cu)
+
+	var edges []cellEdge
+	var collect func(id s2.CellID, k int)
+	collect = func(id s2.CellID, k int) {
+		n := id.EdgeNeighbors()[k]
+		if cu.ContainsCellID(n) {
+			return
+		}
+		if !cu.IntersectsCellID(n) || id.IsLeaf() {
+			cell := s2.CellFromCellID(id)
+			edges = append(edges, cellEdge{cell.Vertex(k), cell.Vertex((k + 1) % 4)})
+			return
+		}
+		// the neighbour is partially covered, continue with the children along the edge
+		for _, ch := range id.Children() {
+			if !id.Contains(ch.EdgeNeighbors()[k]) {
+				collect(ch, k)
+			}
+		}
+	}
+	for _, id := range cu {
+		for k := 0; k < 4; k++ {
+			collect(id, k)
+		}
+	}
+
+	if len(edges) == 0 {
+		if len(cu) > 0 {
+			return s2.FullPolygon()
+		}
+		return s2.PolygonFromLoops([]*s2.Loop{s2.EmptyLoop()})
+	}
+
+	var loops []*s2.Loop
+	for _, pts := range chainEdges(edges) {
+		loops = append(loops, s2.LoopFromPoints(removeCollinear(pts)))
+	}
+	return s2.PolygonFromOrientedLoops(loops)
+}
+
+// TokensToPolygon merges the cells of the tokens to a polygon, with a shell for each disconnected group of
+// cells, skipping invalid tokens
+func TokensToPolygon(tokens []string) *s2.Polygon {
+	var cu s2.CellUnion
+	for _, t := range tokens {
+		if id := s2.CellIDFromToken(strings.TrimSpace(t)); id.IsValid() {
+			cu = append(cu, id)
+		}
+	}
+	return CellUnionToPolygon(cu)
+}
+
+// chainEdges links directed edges sharing end and start vertices to closed chains of vertices
+func chainEdges(edges []cellEdge) [][]s2.Point {
+	byStart := make(map[vertexKey][]int)
+	for i, e := range edges {
+		byStart[keyOf(e.a)] = append(byStart[keyOf(e.a)], i)
+	}
+
+	used := make([]bool, len(edges))
+	next := func(p s2.Point) int {
+		for _, n := range byStart[keyOf(p)] {
+			if !used[n] {
+				return n
+			}
+		}
+		// quantization may split nearly equal vertices to different keys
+		for n, e := range edges {
+			if !used[n] && e.a.ApproxEqual(p) {
+				return n
+			}
+		}
+		return -1
+	}
+
+	var chains [][]s2.Point
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		var pts []s2.Point
+		for j := i; j >= 0; {
+			used[j] = true
+			pts = append(pts, edges[j].a)
+			if edges[j].b.ApproxEqual(edges[i].a) {
+				break
+			}
+			j = next(edges[j].b)
+		}
+		chains = append(chains, pts)
+	}
+	return chains
+}
+
+// removeCollinear drops vertices lying on the great circle through their neighbours
+func removeCollinear(pts []s2.Point) []s2.Point {
+	if len(pts) <= 3 {
+		return pts
+	}
+	var out []s2.Point
+	for i, p := range pts {
+		prev, next := pts[(i+len(pts)-1)%len(pts)], pts[(i+1)%len(pts)]
+		// the triple product vanishes for points on the same great circle
+		sin := math.Abs(p.Dot(prev.Cross(next.Vector))) / (prev.Sub(p.Vector).Norm() * next.Sub(p.Vector).Norm())
+		if sin > 1e-9 {
+			out = append(out, p)
+		}
+	}
+	if len(out) < 3 {
+		return pts
+	}
+	return out
+}
+
+// cellRing returns the closed counterclockwise lng/lat ring of the cell
+func cellRing(id s2.CellID) [][]float64 {
+	var ring [][]float64
+	for _, v := range EdgesOfCell(s2.CellFromCellID(id)) {
+		ring = append(ring, []float64{v[1], v[0]})
+	}
+	return append(ring, ring[0])
+}
+
+// CellUnionToMultiPolygon converts the cells of the union to a single multipolygon of one polygon per cell
+func CellUnionToMultiPolygon(cu s2.CellUnion) *geojson.Geometry {
+	polygons := make([][][][]float64, 0, len(cu))
+	for _, id := range cu {
+		polygons = append(polygons, [][][]float64{cellRing(id)})
+	}
+	return geojson.NewMultiPolygonGeometry(polygons...)
+}
+
+// CellUnionToFeatureCollection converts the cells of the union to polygon features identified by their tokens
+func CellUnionToFeatureCollection(cu s2.CellUnion) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for _, id := range cu {
+		f := geojson.NewPolygonFeature([][][]float64{cellRing(id)})
+		f.ID = id.ToToken()
+		f.SetProperty("token", id.ToToken())
+		f.SetProperty("level", id.Level())
+		fc.AddFeature(f)
+	}
+	return fc
+}
+
+// CoarsenCovering greedily replaces cells with their parents until the normalized union has at most
+// maxCells cells, each time choosing the parent which adds the least area to the covering
+func CoarsenCovering(cu s2.CellUnion, maxCells int) s2.CellUnion {
+	cu = s2.CellUnionFromUnion(cu)
+	for len(cu) > maxCells {
+		best, bestCost := s2.CellID(0), math.Inf(1)
+		for _, id := range cu {
+			if id.Level() == 0 {
+				continue
+			}
+			parent := id.Parent(id.Level() - 1)
+			if parent == best {
+				continue
+			}
+			// the union is sorted, the cells contained by the parent are contiguous
+			start := sort.Search(len(cu), func(i int) bool { return cu[i] >= parent.RangeMin() })
+			covered := 0.0
+			for i := start; i < len(cu) && cu[i] <= parent.RangeMax(); i++ {
+				covered += s2.CellFromCellID(cu[i]).ApproxArea()
+			}
+			if cost := s2.CellFromCellID(parent).ApproxArea() - covered; cost < bestCost {
+				best, bestCost = parent, cost
+			}
+		}
+		if best == 0 {
+			break
+		}
+
+		coarse := s2.CellUnion{best}
+		for _, id := range cu {
+			if !best.Contains(id) {
+				coarse = append(coarse, id)
+			}
+		}
+		coarse.Normalize()
+		cu = coarse
+	}
+	return cu
+}
+
+// CoveringDiff returns the regions covered by b and not a, and covered by a and not b
+func CoveringDiff(a, b s2.CellUnion) (added, removed s2.CellUnion) {
+	a, b = s2.CellUnionFromUnion(a), s2.CellUnionFromUnion(b)
+	return s2.CellUnionFromDifference(b, a), s2.CellUnionFromDifference(a, b)
+}
+
+// CoveringSymmetricDifference returns the regions covered by exactly one of a and b, the union of the
+// added and removed regions of CoveringDiff
+func CoveringSymmetricDifference(a, b s2.CellUnion) s2.CellUnion {
+	added, removed := CoveringDiff(a, b)
+	return s2.CellUnionFromUnion(added, removed)
+}
+
+// CellUnionContainsPoint checks if the leaf cell of the point is contained by the union. The check is
+// only as accurate as the cells of the union, points near the covered region may match as well.
+func CellUnionContainsPoint(cu s2.CellUnion, pt Point) bool {
+	return cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(pt.Lat, pt.Lng)))
+}
+
+// MergeSmallCells replaces the cells with an area below minAreaSqm square meters by their deepest ancestor
+// reaching the area, trading accuracy along the edges of the covered region for fewer cells
+func MergeSmallCells(cu s2.CellUnion, minAreaSqm float64) s2.CellUnion {
+	merged := make(s2.CellUnion, 0, len(cu))
+	for _, id := range cu {
+		for id.Level() > 0 && cellAreaSqm(id) < minAreaSqm {
+			id = id.Parent(id.Level() - 1)
+		}
+		merged = append(merged, id)
+	}
+	merged.Normalize()
+	return merged
+}
+
+func cellAreaSqm(id s2.CellID) float64 {
+	r := EarthRadius * 1000
+	return s2.CellFromCellID(id).ApproxArea() * r * r
+}
+
+// GroupByLevel returns the tokens of the cells of the union by their level, in the order of the union
+func GroupByLevel(cu s2.CellUnion) map[int][]string {
+	levels := make(map[int][]string)
+	for _, id := range cu {
+		levels[id.Level()] = append(levels[id.Level()], id.ToToken())
+	}
+	return levels
+}
+
+// CoveringFaces returns the distinct cube faces of the cells of the union in ascending order
+func CoveringFaces(cu s2.CellUnion) []int {
+	var seen [6]bool
+	for _, id := range cu {
+		seen[id.Face()] = true
+	}
+	faces := []int{}
+	for f, ok := range seen {
+		if ok {
+			faces = append(faces, f)
+		}
+	}
+	return faces
+}
+
+// BoundaryCells returns the cells of the union with at least one edge neighbor of the same level not
+// contained by the union, the outline of the covered region
+func BoundaryCells(cu s2.CellUnion) s2.CellUnion {
+	norm := s2.CellUnionFromUnion(cu)
+	boundary := s2.CellUnion{}
+	for _, id := range cu {
+		for _, n := range id.EdgeNeighbors() {
+			if !norm.ContainsCellID(n) {
+				boundary = append(boundary, id)
+				break
+			}
+		}
+	}
+	return boundary
+}
+
+// BufferCovering grows the covering by n rings of edge neighbors of its boundary cells, each ring about a cell
+// of the boundary level wide, so that the coverings of adjacent regions overlap along their shared border
+func BufferCovering(cu s2.CellUnion, n int) s2.CellUnion {
+	// the boundary is taken before normalizing so that the ring cells keep the levels of the covering cells
+	buffered, frontier := s2.CellUnionFromUnion(cu), BoundaryCells(cu)
+	for i := 0; i < n && len(frontier) > 0; i++ {
+		var ring s2.CellUnion
+		for _, id := range frontier {
+			for _, nb := range id.EdgeNeighbors() {
+				if !buffered.ContainsCellID(nb) {
+					ring = append(ring, nb)
+				}
+			}
+		}
+		buffered = s2.CellUnionFromUnion(buffered, ring)
+		frontier = ring
+	}
+	return buffered
+}
+
+// CoveringAdjacency returns the graph of the cells of the covering sharing an edge, from the token of each cell
+// to the sorted tokens of its neighbors. Cells of different levels are adjacent when an edge neighbor of the
+// smaller one is within the larger one. Duplicate cells and cells within others are skipped, but unlike
+// normalizing siblings are not merged so the graph keys are the covering tokens.
+func CoveringAdjacency(cu s2.CellUnion) map[string][]string {
+	sorted := append(s2.CellUnion(nil), cu...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].RangeMin() != sorted[j].RangeMin() {
+			return sorted[i].RangeMin() < sorted[j].RangeMin()
+		}
+		return sorted[i].RangeMax() > sorted[j].RangeMax()
+	})
+	var norm s2.CellUnion
+	for _, id := range sorted {
+		if len(norm) == 0 || !norm[len(norm)-1].Contains(id) {
+			norm = append(norm, id)
+		}
+	}
+	adjacency := make(map[string][]string, len(norm))
+	for _, a := range norm {
+		neighbors := map[s2.CellID]bool{}
+		for _, nb := range a.EdgeNeighbors() {
+			// the covering cells overlapping the neighbor, a larger one containing it or smaller ones within it
+			i := sort.Search(len(norm), func(i int) bool { return norm[i].RangeMax() >= nb.RangeMin() })
+			for ; i < len(norm) && norm[i].RangeMin() <= nb.RangeMax(); i++ {
+				b := norm[i]
+				if b.Contains(nb) {
+					neighbors[b] = true
+					continue
+				}
+				for _, bnb := range b.EdgeNeighbors() {
+					if a.Contains(bnb) {
+						neighbors[b] = true
+						break
+					}
+				}
+			}
+		}
+		tokens := []string{}
+		for id := range neighbors {
+			tokens = append(tokens, id.ToToken())
+		}
+		sort.Strings(tokens)
+		adjacency[a.ToToken()] = tokens
+	}
+	return adjacency
+}
+
+// SortByDistance returns the cells of the union sorted by the distance of their centers to the point,
+// ties in the order of the union
+func SortByDistance(cu s2.CellUnion, p Point) s2.CellUnion {
+	ref := s2.PointFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lng))
+	distances := make(map[s2.CellID]s1.Angle, len(cu))
+	for _, id := range cu {
+		distances[id] = id.Point().Distance(ref)
+	}
+	sorted := append(s2.CellUnion{}, cu...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return distances[sorted[i]] < distances[sorted[j]]
+	})
+	return sorted
+}
+
+// MarshalCellUnion encodes the cells as a little endian uint32 count followed by the little endian uint64 id
+// of each cell, 8 bytes per cell against the about 10 bytes of a quoted token
+func MarshalCellUnion(cu s2.CellUnion) []byte {
+	b := make([]byte, 4+8*len(cu))
+	binary.LittleEndian.PutUint32(b, uint32(len(cu)))
+	for i, id := range cu {
+		binary.LittleEndian.PutUint64(b[4+8*i:], uint64(id))
+	}
+	return b
+}
+
+// UnmarshalCellUnion decodes the cells encoded by MarshalCellUnion, failing when the length does not match
+// the count or a cell id is invalid
+func UnmarshalCellUnion(b []byte) (s2.CellUnion, error) {
+	if len(b) < 4 {
+		return nil, ErrInvalidCellUnionEncoding
+	}
+	n := binary.LittleEndian.Uint32(b)
+	if uint64(len(b)-4) != 8*uint64(n) {
+		return nil, ErrInvalidCellUnionEncoding
+	}
+	cu := make(s2.CellUnion, n)
+	for i := range cu {
+		cu[i] = s2.CellID(binary.LittleEndian.Uint64(b[4+8*i:]))
+		if !cu[i].IsValid() {
+			return nil, fmt.Errorf("cell %d: %v", i, ErrInvalidCellUnionEncoding)
+		}
+	}
+	return cu, nil
+}
@@ -0,0 +1,290 @@
+package geo
+
+import (
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestCellUnionToPolygon(t *testing.T) {
+	// a level 10 cell and the children of its right neighbour merge to one square
+	id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(38.34, 23.44)).Parent(10)
+	right := id.EdgeNeighbors()[1].Children()
+	cu := s2.CellUnion{id, right[0], right[1], right[2], right[3]}
+
+	p := CellUnionToPolygon(cu)
+	assert.NoError(t, p.Validate())
+	assert.Equal(t, 1, p.NumLoops())
+	assert.Equal(t, 4, p.Loop(0).NumVertices())
+	assert.InDelta(t, cu.ExactArea(), p.Area(), 1e-15)
+
+	// mixed levels along the shared edge
+	cu = s2.CellUnion{id, right[0], right[3]}
+	p = CellUnionToPolygon(cu)
+	assert.NoError(t, p.Validate())
+	assert.Equal(t, 1, p.NumLoops())
+	cu.Normalize()
+	assert.InDelta(t, cu.ExactArea(), p.Area(), 1e-15)
+
+	// a ring of eight cells results in a shell with a hole
+	var ring s2.CellUnion
+	for _, n := range id.AllNeighbors(10) {
+		ring = append(ring, n)
+	}
+	p = CellUnionToPolygon(ring)
+	assert.NoError(t, p.Validate())
+	assert.Equal(t, 2, p.NumLoops())
+	assert.True(t, p.Loop(1).IsHole())
+	assert.False(t, p.ContainsPoint(id.Point()))
+	assert.True(t, p.ContainsPoint(id.EdgeNeighbors()[0].Point()))
+
+	assert.True(t, CellUnionToPolygon(nil).IsEmpty())
+}
+
+func TestCellUnionToFeatureCollection(t *testing.T) {
+	cu := s2.CellUnion{s2.CellIDFromToken("14"), s2.CellIDFromToken("1c")}
+	fc := CellUnionToFeatureCollection(cu)
+	assert.Equal(t, 2, len(fc.Features))
+
+	f := fc.Features[0]
+	assert.Equal(t, "14", f.ID)
+	assert.Equal(t, "14", f.Properties["token"])
+	assert.Equal(t, 1, f.Properties["level"])
+	assert.True(t, f.Geometry.IsPolygon())
+	ring := f.Geometry.Polygon[0]
+	assert.Equal(t, 5, len(ring))
+	assert.Equal(t, ring[0], ring[4])
+	assert.True(t, ringArea(ring) > 0)
+}
+
+func TestCoarsenCovering(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	cu, _, _, _ := CoverPolygon(p, 12, 1)
+	cu.Normalize()
+	assert.True(t, len(cu) > 10)
+
+	coarse := CoarsenCovering(cu, 10)
+	assert.True(t, len(coarse) <= 10)
+	assert.True(t, coarse.IsNormalized())
+	assert.True(t, coarse.Contains(cu))
+
+	// unions within the limit are only normalized
+	assert.Equal(t, cu, CoarsenCovering(cu, len(cu)))
+
+	faces := CoarsenCovering(cu, 0)
+	for _, id := range faces {
+		assert.Equal(t, 0, id.Level())
+	}
+}
+
+func TestCoveringDiff(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	coarse, _, _, _ := CoverPolygon(p, 6, 1)
+	fine, _, _, _ := CoverPolygon(p, 10, 1)
+
+	added, removed := CoveringDiff(coarse, fine)
+	assert.True(t, len(removed) > 0)
+	assert.False(t, coarse.Intersects(added))
+	assert.False(t, fine.Intersects(removed))
+
+	added, removed = CoveringDiff(fine, fine)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+}
+
+func TestCoveringSymmetricDifference(t *testing.T) {
+	a := s2.CellUnion{s2.CellIDFromFace(0).Children()[0], s2.CellIDFromFace(0).Children()[1]}
+	b := s2.CellUnion{s2.CellIDFromFace(0).Children()[1], s2.CellIDFromFace(0).Children()[2]}
+
+	diff := CoveringSymmetricDifference(a, b)
+	assert.Equal(t, s2.CellUnion{s2.CellIDFromFace(0).Children()[0], s2.CellIDFromFace(0).Children()[2]}, diff)
+	assert.Equal(t, diff, CoveringSymmetricDifference(b, a))
+	assert.Empty(t, CoveringSymmetricDifference(a, a))
+
+	// a cell and one of its children differ by the other children
+	diff = CoveringSymmetricDifference(s2.CellUnion{s2.CellIDFromFace(1)}, s2.CellUnion{s2.CellIDFromFace(1).Children()[3]})
+	assert.Equal(t, s2.CellUnion{s2.CellIDFromFace(1).Children()[0], s2.CellIDFromFace(1).Children()[1], s2.CellIDFromFace(1).Children()[2]}, diff)
+}
+
+func TestGroupByLevel(t *testing.T) {
+	face := s2.CellIDFromFace(1)
+	children := face.Children()
+	cu := s2.CellUnion{children[0], children[1].Children()[2], children[3], face.Parent(0)}
+	assert.Equal(t, map[int][]string{
+		0: {"3"},
+		1: {children[0].ToToken(), children[3].ToToken()},
+		2: {children[1].Children()[2].ToToken()},
+	}, GroupByLevel(cu))
+	assert.Empty(t, GroupByLevel(nil))
+}
+
+func TestBufferCovering(t *testing.T) {
+	center := s2.CellIDFromLatLng(s2.LatLngFromDegrees(10, 10)).Parent(10)
+	cu := s2.CellUnion{center}
+
+	once := BufferCovering(cu, 1)
+	assert.True(t, once.ContainsCellID(center))
+	for _, nb := range center.EdgeNeighbors() {
+		assert.True(t, once.ContainsCellID(nb))
+	}
+
+	twice := BufferCovering(cu, 2)
+	assert.True(t, twice.Contains(once))
+	assert.True(t, twice.ApproxArea() > once.ApproxArea())
+	assert.Equal(t, s2.CellUnionFromUnion(cu), BufferCovering(cu, 0))
+
+	// a ring of cells of the boundary level beyond the edge of a square
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {0.1, 0}, {0.1, 0.1}, {0, 0.1}, {0, 0}})
+	covering, _, _, _ := CoverPolygon(p, 12, 12)
+	buffered := BufferCovering(covering, 1)
+	outside := s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.05, 0.115)).Parent(12)
+	assert.False(t, covering.ContainsCellID(outside))
+	assert.True(t, buffered.ContainsCellID(outside))
+	assert.False(t, buffered.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.05, 0.16))))
+}
+
+func TestCellUnionContainsPoint(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	cu, _, _, _ := CoverPolygon(p, 10, 1)
+
+	assert.True(t, CellUnionContainsPoint(cu, Point{Lat: 0.5, Lng: 0.5}))
+	assert.False(t, CellUnionContainsPoint(cu, Point{Lat: 5, Lng: 5}))
+	assert.False(t, CellUnionContainsPoint(nil, Point{Lat: 0.5, Lng: 0.5}))
+}
+
+func TestMergeSmallCells(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	cu, _, _, _ := CoverPolygon(p, 14, 1)
+
+	// about the area of a level 10 cell
+	minArea := 1e8
+	merged := MergeSmallCells(cu, minArea)
+	assert.True(t, len(merged) < len(cu))
+	assert.True(t, merged.Contains(cu))
+	for _, id := range merged {
+		assert.True(t, cellAreaSqm(id) >= minArea)
+	}
+
+	assert.Equal(t, 1, len(MergeSmallCells(cu, 1e15)))
+}
+
+func TestCoveringFaces(t *testing.T) {
+	cu := s2.CellUnion{s2.CellIDFromFace(4).ChildBegin(), s2.CellIDFromFace(1), s2.CellIDFromFace(4).ChildEnd().Prev()}
+	assert.Equal(t, []int{1, 4}, CoveringFaces(cu))
+	assert.Equal(t, []int{}, CoveringFaces(nil))
+}
+
+func TestTokensToPolygon(t *testing.T) {
+	a := s2.CellIDFromLatLng(s2.LatLngFromDegrees(38.34, 23.44)).Parent(10)
+	b := s2.CellIDFromLatLng(s2.LatLngFromDegrees(-10, 100)).Parent(10)
+
+	p := TokensToPolygon([]string{a.ToToken(), " " + a.EdgeNeighbors()[1].ToToken()})
+	assert.Equal(t, 1, p.NumLoops())
+
+	// disconnected cells result in multiple shells, invalid tokens are skipped
+	p = TokensToPolygon([]string{a.ToToken(), b.ToToken(), "x"})
+	assert.NoError(t, p.Validate())
+	assert.Equal(t, 2, p.NumLoops())
+	assert.True(t, PolygonToGeoJSON(p).IsMultiPolygon())
+
+	assert.True(t, TokensToPolygon(nil).IsEmpty())
+}
+
+func TestCellUnionToMultiPolygon(t *testing.T) {
+	cu := s2.CellUnion{s2.CellIDFromToken("14"), s2.CellIDFromToken("1c")}
+	g := CellUnionToMultiPolygon(cu)
+	assert.True(t, g.IsMultiPolygon())
+	assert.Equal(t, 2, len(g.MultiPolygon))
+	assert.Equal(t, CellUnionToFeatureCollection(cu).Features[1].Geometry.Polygon, g.MultiPolygon[1])
+
+	assert.Empty(t, CellUnionToMultiPolygon(nil).MultiPolygon)
+}
+
+func TestBoundaryCells(t *testing.T) {
+	// the 3x3 block of cells around a cell has the center cell as only inner cell
+	center := s2.CellIDFromLatLng(s2.LatLngFromDegrees(10, 10)).Parent(10)
+	cu := s2.CellUnion{center}
+	for _, n := range center.AllNeighbors(10) {
+		cu = append(cu, n)
+	}
+	boundary := BoundaryCells(cu)
+	assert.Equal(t, 8, len(boundary))
+	assert.False(t, boundary.ContainsCellID(center))
+
+	assert.Empty(t, BoundaryCells(nil))
+}
+
+func TestSortByDistance(t *testing.T) {
+	a := s2.CellIDFromLatLng(s2.LatLngFromDegrees(0, 0)).Parent(10)
+	b := s2.CellIDFromLatLng(s2.LatLngFromDegrees(10, 10)).Parent(10)
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(5, 5)).Parent(10)
+	cu := s2.CellUnion{a, b, c}
+
+	assert.Equal(t, s2.CellUnion{b, c, a}, SortByDistance(cu, Point{Lat: 11, Lng: 11}))
+	assert.Equal(t, s2.CellUnion{a, c, b}, SortByDistance(cu, Point{Lat: 0, Lng: 0}))
+	assert.Equal(t, s2.CellUnion{a, b, c}, cu)
+}
+
+func TestMarshalCellUnion(t *testing.T) {
+	cu := s2.CellUnion{
+		s2.CellIDFromToken("89c25"),
+		s2.CellIDFromLatLng(s2.LatLngFromDegrees(10, 10)),
+		s2.CellIDFromFace(5),
+	}
+	b := MarshalCellUnion(cu)
+	assert.Equal(t, 4+8*len(cu), len(b))
+	assert.Equal(t, []byte{3, 0, 0, 0}, b[:4])
+	decoded, err := UnmarshalCellUnion(b)
+	assert.NoError(t, err)
+	assert.Equal(t, cu, decoded)
+
+	empty, err := UnmarshalCellUnion(MarshalCellUnion(nil))
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = UnmarshalCellUnion(b[:3])
+	assert.Equal(t, ErrInvalidCellUnionEncoding, err)
+	_, err = UnmarshalCellUnion(b[:len(b)-1])
+	assert.Equal(t, ErrInvalidCellUnionEncoding, err)
+	_, err = UnmarshalCellUnion([]byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	assert.EqualError(t, err, "cell 0: invalid cell union encoding")
+}
+
+func TestCoveringAdjacency(t *testing.T) {
+	center := s2.CellIDFromLatLng(s2.LatLngFromDegrees(10, 10)).Parent(10)
+	nbs := center.EdgeNeighbors()
+	cu := s2.CellUnion{center, nbs[0], nbs[2], nbs[3]}
+
+	// the east neighbor is partly covered by the children touching the center and one that does not
+	var touching []string
+	far := false
+	for _, child := range nbs[1].Children() {
+		touches := false
+		for _, nb := range child.EdgeNeighbors() {
+			touches = touches || center.Contains(nb)
+		}
+		if touches {
+			touching = append(touching, child.ToToken())
+			cu = append(cu, child)
+		} else if !far {
+			far = true
+			cu = append(cu, child)
+		}
+	}
+	assert.Equal(t, 2, len(touching))
+
+	adjacency := CoveringAdjacency(cu)
+	assert.Equal(t, 7, len(adjacency))
+	want := append([]string{nbs[0].ToToken(), nbs[2].ToToken(), nbs[3].ToToken()}, touching...)
+	sort.Strings(want)
+	assert.Equal(t, want, adjacency[center.ToToken()])
+
+	// the graph is symmetric
+	for a, neighbors := range adjacency {
+		for _, b := range neighbors {
+			assert.Contains(t, adjacency[b], a)
+		}
+	}
+	assert.Equal(t, []string{}, CoveringAdjacency(s2.CellUnion{center})[center.ToToken()])
+}
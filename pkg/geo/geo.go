@@ -1,20 +1,49 @@
 package geo
 
 import (
+	"errors"
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
+	"github.com/pantrif/s2-geojson/pkg/logger"
 	"github.com/paulmach/go.geojson"
+	"math"
 )
 
 const (
 	//EarthRadius the radius of earth in kilometers
 	EarthRadius = 6371.01
 	maxCells    = 100
+	levelMod    = 1
+	// MaxUniformCells is the max estimated number of cells of a uniform covering
+	MaxUniformCells = 100000
+	// MaxBandCells is the max number of cells CoverBorderBand visits subdividing the polygon covering
+	MaxBandCells = 20000
+)
+
+var (
+	// ErrEmptyRing is returned for rings without positions
+	ErrEmptyRing = errors.New("empty ring")
+	// ErrDegenerateRing is returned for rings of less than 3 distinct vertices
+	ErrDegenerateRing = errors.New("degenerate ring: less than 3 distinct vertices")
+	// ErrEmptyPolygon is returned when covering a polygon without loops
+	ErrEmptyPolygon = errors.New("empty polygon")
+	// ErrTooManyCells is returned when a covering is estimated to have more cells than its limit
+	ErrTooManyCells = errors.New("too many cells")
 )
 
 // Point struct contains the lat/lng of a point
 type Point struct {
-	Lat float64
-	Lng float64
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// LevelStats struct contains summary statistics of the cell levels of a covering
+type LevelStats struct {
+	MinLevel  int     `json:"min_level"`
+	MaxLevel  int     `json:"max_level"`
+	AvgLevel  float64 `json:"avg_level"`
+	CellCount int     `json:"cell_count"`
 }
 
 // DecodeGeoJSON decodes a feature collection
@@ -26,41 +55,780 @@ func DecodeGeoJSON(json []byte) ([]*geojson.Feature, error) {
 	return f.Features, nil
 }
 
-// PointsToPolygon converts points to s2 polygon
-func PointsToPolygon(points [][]float64) *s2.Polygon {
+// PointsToPolygon converts points to s2 polygon, failing for empty rings and rings of less than 3 distinct vertices
+func PointsToPolygon(points [][]float64) (*s2.Polygon, error) {
+	if _, err := validateRing(points); err != nil {
+		return nil, err
+	}
 	var pts []s2.Point
 	for _, pt := range points {
 		pts = append(pts, s2.PointFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0])))
 	}
 	loop := s2.LoopFromPoints(pts)
 
-	return s2.PolygonFromLoops([]*s2.Loop{loop})
+	return s2.PolygonFromLoops([]*s2.Loop{loop}), nil
 }
 
-// CoverPolygon converts s2 polygon to cell union and returns the respective cells
-func CoverPolygon(p *s2.Polygon, maxLevel, minLevel int) (s2.CellUnion, []string, [][][]float64) {
-	var tokens []string
-	var s2cells [][][]float64
+// PointsToPolygonSnapped converts points to s2 polygon like PointsToPolygon after rounding the coordinates to the
+// decimals, collapsing the consecutive near-coincident vertices caused by measurement jitter. A negative decimals
+// disables the snapping, it fails as well for rings left with less than 3 distinct vertices.
+func PointsToPolygonSnapped(points [][]float64, decimals int) (*s2.Polygon, error) {
+	if decimals < 0 {
+		return PointsToPolygon(points)
+	}
+	if _, err := validateRing(points); err != nil {
+		return nil, err
+	}
+	return PointsToPolygon(snapRing(points, decimals))
+}
 
-	rc := &s2.RegionCoverer{MaxLevel: maxLevel, MinLevel: minLevel, MaxCells: maxCells}
-	r := s2.Region(p)
-	covering := rc.Covering(r)
+// validateRing fails for empty rings, invalid coordinates and rings of less than 3 distinct vertices,
+// returning the ring without its closing vertex otherwise
+func validateRing(points [][]float64) ([][]float64, error) {
+	if len(points) == 0 {
+		return nil, ErrEmptyRing
+	}
+	for _, pt := range points {
+		if len(pt) < 2 {
+			return nil, errors.New("invalid coordinate in ring")
+		}
+	}
+	n := len(points)
+	if n > 1 && samePosition(points[0], points[n-1]) {
+		n--
+	}
+	if distinctPositions(points[:n], 3) < 3 {
+		return nil, ErrDegenerateRing
+	}
+	return points[:n], nil
+}
+
+// distinctPositions counts the distinct positions, stopping at max
+func distinctPositions(points [][]float64, max int) int {
+	seen := make(map[[2]float64]bool)
+	for _, pt := range points {
+		seen[[2]float64{pt[0], pt[1]}] = true
+		if len(seen) >= max {
+			break
+		}
+	}
+	return len(seen)
+}
+
+// snapRing rounds the coordinates of the valid ring to the decimals and drops the vertices equal to their predecessor
+func snapRing(points [][]float64, decimals int) [][]float64 {
+	scale := math.Pow(10, float64(decimals))
+	snapped := make([][]float64, 0, len(points))
+	for _, pt := range points {
+		s := []float64{math.Round(pt[0]*scale) / scale, math.Round(pt[1]*scale) / scale}
+		if len(snapped) > 0 && samePosition(snapped[len(snapped)-1], s) {
+			continue
+		}
+		snapped = append(snapped, s)
+	}
+	return snapped
+}
+
+// RepairPolygon closes an open ring, removes duplicate vertices and spikes and fixes the winding
+// of the ring so that it can be converted to a valid s2 loop. Applied repairs are logged at debug level.
+func RepairPolygon(points [][]float64) ([][]float64, error) {
+	var ring [][]float64
+	duplicates := 0
+	for _, pt := range points {
+		if len(pt) < 2 {
+			return nil, errors.New("invalid coordinate in ring")
+		}
+		if len(ring) > 0 && samePosition(ring[len(ring)-1], pt) {
+			duplicates++
+			continue
+		}
+		ring = append(ring, pt)
+	}
+	if duplicates > 0 {
+		logger.Debug("repair", "action", "removed duplicate vertices", "count", duplicates)
+	}
+	if len(ring) > 1 && samePosition(ring[0], ring[len(ring)-1]) {
+		ring = ring[:len(ring)-1]
+	} else if len(ring) > 0 {
+		logger.Debug("repair", "action", "closed open ring")
+	}
 
-	for _, c := range covering {
-		cell := s2.CellFromCellID(s2.CellIDFromToken(c.ToToken()))
+	for removed := true; removed && len(ring) >= 3; {
+		removed = false
+		for i := range ring {
+			prev, next := ring[(i+len(ring)-1)%len(ring)], ring[(i+1)%len(ring)]
+			if samePosition(prev, next) {
+				// drop the spike and one of the coincident neighbours
+				if j := (i + 1) % len(ring); j > i {
+					ring = append(ring[:i], ring[j+1:]...)
+				} else {
+					ring = ring[1:i]
+				}
+				logger.Debug("repair", "action", "removed spike")
+				removed = true
+				break
+			}
+		}
+	}
+	if len(ring) < 3 {
+		return nil, errors.New("ring has less than 3 distinct vertices")
+	}
+
+	var pts []s2.Point
+	for _, pt := range ring {
+		pts = append(pts, s2.PointFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0])))
+	}
+	loop := s2.LoopFromPoints(pts)
+	if loop.Area() > 2*math.Pi {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+		loop = s2.LoopFromPoints(pts)
+		logger.Debug("repair", "action", "reversed ring winding")
+	}
+	if err := loop.Validate(); err != nil {
+		return nil, err
+	}
+
+	return append(ring, ring[0]), nil
+}
+
+// FixRingWinding returns the ring reversed when it winds clockwise as a s2 loop, that is when the loop
+// covers more than a hemisphere, so that it is covered as the region it outlines rather than its
+// complement. Rings larger than a hemisphere are inverted as well, as the winding is their only difference.
+func FixRingWinding(points [][]float64) [][]float64 {
+	ring := points
+	if len(ring) > 1 && samePosition(ring[0], ring[len(ring)-1]) {
+		ring = ring[:len(ring)-1]
+	}
+	if distinctPositions(ring, 3) < 3 || s2.LoopFromPoints(ringPoints(ring)).Area() <= 2*math.Pi {
+		return points
+	}
+	reversed := make([][]float64, len(points))
+	for i, pt := range points {
+		reversed[len(points)-1-i] = pt
+	}
+	return reversed
+}
+
+func samePosition(a, b []float64) bool {
+	return a[0] == b[0] && a[1] == b[1]
+}
+
+// GeometryToPolygon converts the rings of a polygon or multipolygon geometry to a single s2 polygon,
+// holes are determined by the nesting of the rings. It fails for empty and degenerate rings as PointsToPolygon.
+func GeometryToPolygon(g *geojson.Geometry) (*s2.Polygon, error) {
+	rings := PolygonRings(g)
+	if len(rings) == 0 {
+		return nil, errors.New("geometry is not a polygon")
+	}
+	return ringsToPolygon(rings)
+}
+
+// ringsToPolygon converts the rings to a s2 polygon whose holes are determined by the nesting of the rings,
+// each ring is normalized to cover at most half of the sphere so that its winding does not matter
+func ringsToPolygon(rings [][][]float64) (*s2.Polygon, error) {
+	var loops []*s2.Loop
+	for _, ring := range rings {
+		ring, err := validateRing(ring)
+		if err != nil {
+			return nil, err
+		}
+		loop := s2.LoopFromPoints(ringPoints(ring))
+		loop.Normalize()
+		loops = append(loops, loop)
+	}
+	return s2.PolygonFromLoops(loops), nil
+}
+
+// PolygonContainsPolygon checks if the inner polygon is entirely within the outer polygon
+func PolygonContainsPolygon(outer, inner *s2.Polygon) bool {
+	return outer.Contains(inner)
+}
+
+// IntersectionMatrix returns the symmetric matrix of which polygons intersect which, the pairs whose bounding
+// rects are disjoint are skipped without testing the polygons
+func IntersectionMatrix(polygons []*s2.Polygon) [][]bool {
+	m := make([][]bool, len(polygons))
+	for i := range m {
+		m[i] = make([]bool, len(polygons))
+	}
+	for i, a := range polygons {
+		m[i][i] = !a.IsEmpty()
+		for j := i + 1; j < len(polygons); j++ {
+			b := polygons[j]
+			if a.RectBound().Intersects(b.RectBound()) && a.Intersects(b) {
+				m[i][j], m[j][i] = true, true
+			}
+		}
+	}
+	return m
+}
 
-		s2cells = append(s2cells, EdgesOfCell(cell))
+// PolygonToGeoJSON converts s2 polygon to a geojson polygon, or multipolygon when it has multiple shells
+func PolygonToGeoJSON(p *s2.Polygon) *geojson.Geometry {
+	var polygons [][][][]float64
+	for k, l := range p.Loops() {
+		if l.IsHole() {
+			continue
+		}
+		polygon := [][][]float64{loopToRing(l, false)}
+		// the immediate children of a shell are its holes, skip the descendants of each hole
+		for h := k + 1; h <= p.LastDescendant(k); h = p.LastDescendant(h) + 1 {
+			polygon = append(polygon, loopToRing(p.Loop(h), true))
+		}
+		polygons = append(polygons, polygon)
+	}
+	if len(polygons) == 1 {
+		return geojson.NewPolygonGeometry(polygons[0])
+	}
+	return geojson.NewMultiPolygonGeometry(polygons...)
+}
+
+// loopToRing converts the loop to a closed ring of [lng, lat] positions, reversed for holes
+func loopToRing(l *s2.Loop, reverse bool) [][]float64 {
+	var ring [][]float64
+	for _, v := range l.Vertices() {
+		ll := s2.LatLngFromPoint(v)
+		ring = append(ring, []float64{ll.Lng.Degrees(), ll.Lat.Degrees()})
+	}
+	if reverse {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+	if len(ring) > 0 {
+		ring = append(ring, ring[0])
+	}
+	return ring
+}
+
+// EnforceGeoJSONWinding orients the polygon rings of the feature following the right-hand rule of RFC 7946,
+// exterior rings counterclockwise and holes clockwise
+func EnforceGeoJSONWinding(f *geojson.Feature) {
+	if f.Geometry == nil {
+		return
+	}
+	var polygons [][][][]float64
+	if f.Geometry.IsPolygon() {
+		polygons = [][][][]float64{f.Geometry.Polygon}
+	}
+	if f.Geometry.IsMultiPolygon() {
+		polygons = f.Geometry.MultiPolygon
+	}
+	for _, polygon := range polygons {
+		for i, ring := range polygon {
+			if (i == 0) != (ringArea(ring) > 0) {
+				for a, b := 0, len(ring)-1; a < b; a, b = a+1, b-1 {
+					ring[a], ring[b] = ring[b], ring[a]
+				}
+			}
+		}
+	}
+}
+
+// ringArea returns the signed planar area of the ring, positive for counterclockwise rings
+func ringArea(ring [][]float64) float64 {
+	area := 0.0
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+// PolygonRings returns the rings of polygon and multipolygon geometries
+func PolygonRings(g *geojson.Geometry) [][][]float64 {
+	if g == nil {
+		return nil
+	}
+	if g.IsPolygon() {
+		return g.Polygon
+	}
+	var rings [][][]float64
+	if g.IsMultiPolygon() {
+		for _, p := range g.MultiPolygon {
+			rings = append(rings, p...)
+		}
+	}
+	return rings
+}
+
+// polygonParts returns the rings of each polygon of polygon and multipolygon geometries, its shell and holes
+func polygonParts(g *geojson.Geometry) [][][][]float64 {
+	if g == nil {
+		return nil
+	}
+	if g.IsPolygon() {
+		return [][][][]float64{g.Polygon}
+	}
+	if g.IsMultiPolygon() {
+		return g.MultiPolygon
+	}
+	return nil
+}
+
+// PointPositions returns the positions of point and multipoint geometries
+func PointPositions(g *geojson.Geometry) [][]float64 {
+	if g == nil {
+		return nil
+	}
+	if g.IsPoint() {
+		return [][]float64{g.Point}
+	}
+	if g.IsMultiPoint() {
+		return g.MultiPoint
+	}
+	return nil
+}
+
+// LineStrings returns the lines of linestring and multilinestring geometries
+func LineStrings(g *geojson.Geometry) [][][]float64 {
+	if g == nil {
+		return nil
+	}
+	if g.IsLineString() {
+		return [][][]float64{g.LineString}
+	}
+	if g.IsMultiLineString() {
+		return g.MultiLineString
+	}
+	return nil
+}
+
+// ElevationRange returns the min and max elevation, the third coordinate, of the positions of the polygons,
+// points and lines of the features. It reports false when no position has an elevation.
+func ElevationRange(fs []*geojson.Feature) (min, max float64, ok bool) {
+	min, max = math.Inf(1), math.Inf(-1)
+	add := func(positions [][]float64) {
+		for _, pt := range positions {
+			if len(pt) > 2 {
+				min, max, ok = math.Min(min, pt[2]), math.Max(max, pt[2]), true
+			}
+		}
+	}
+	for _, f := range fs {
+		for _, ring := range PolygonRings(f.Geometry) {
+			add(ring)
+		}
+		add(PointPositions(f.Geometry))
+		for _, line := range LineStrings(f.Geometry) {
+			add(line)
+		}
+	}
+	if !ok {
+		return 0, 0, false
+	}
+	return min, max, true
+}
+
+// DensifyPolygon inserts vertices interpolated in lng/lat along the edges of the ring
+// so that no edge is longer than maxSegmentMeters, the elevation is interpolated when both ends have one
+func DensifyPolygon(points [][]float64, maxSegmentMeters float64) [][]float64 {
+	if maxSegmentMeters <= 0 || len(points) < 2 {
+		return points
+	}
+	dense := [][]float64{points[0]}
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		dist := s2.LatLngFromDegrees(a[1], a[0]).Distance(s2.LatLngFromDegrees(b[1], b[0])).Radians() * EarthRadius * 1000
+		n := int(math.Ceil(dist / maxSegmentMeters))
+		for j := 1; j < n; j++ {
+			f := float64(j) / float64(n)
+			v := []float64{a[0] + (b[0]-a[0])*f, a[1] + (b[1]-a[1])*f}
+			if len(a) > 2 && len(b) > 2 {
+				v = append(v, a[2]+(b[2]-a[2])*f)
+			}
+			dense = append(dense, v)
+		}
+		dense = append(dense, b)
+	}
+	return dense
+}
+
+// FeaturePolygons converts each polygon of the features, its shell and holes, to a s2 polygon
+func FeaturePolygons(fs []*geojson.Feature) []*s2.Polygon {
+	var polygons []*s2.Polygon
+	for _, f := range fs {
+		for _, rings := range polygonParts(f.Geometry) {
+			// polygons with empty or degenerate rings contain no points
+			if polygon, err := ringsToPolygon(rings); err == nil {
+				polygons = append(polygons, polygon)
+			}
+		}
+	}
+	return polygons
+}
+
+// PolygonsContainPoint checks if any of the polygons contains the point
+func PolygonsContainPoint(polygons []*s2.Polygon, p Point) bool {
+	pt := s2.PointFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lng))
+	for _, polygon := range polygons {
+		if polygon.ContainsPoint(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// PointsOutside returns the ascending indices of the points contained by none of the polygons
+func PointsOutside(polygons []*s2.Polygon, points []Point) []int {
+	outside := []int{}
+	for i, p := range points {
+		if !PolygonsContainPoint(polygons, p) {
+			outside = append(outside, i)
+		}
+	}
+	return outside
+}
+
+// FeatureContainsPoint checks if any of the polygons of the feature contains the point, outside of their holes
+func FeatureContainsPoint(f *geojson.Feature, p Point) bool {
+	return PolygonsContainPoint(FeaturePolygons([]*geojson.Feature{f}), p)
+}
+
+// newCoverer returns a coverer with all of its fields set, so that the coverings do not depend on the defaults
+// of the s2 library version
+func newCoverer(maxLevel, minLevel, maxCells int) *s2.RegionCoverer {
+	return &s2.RegionCoverer{MinLevel: minLevel, MaxLevel: maxLevel, LevelMod: levelMod, MaxCells: maxCells}
+}
+
+// CoverPolygon converts s2 polygon to cell union and returns the respective cells, failing for empty polygons.
+// The coverer uses the given levels, a MaxCells of 100 and a LevelMod of 1.
+func CoverPolygon(p *s2.Polygon, maxLevel, minLevel int) (s2.CellUnion, []string, [][][]float64, error) {
+	if p == nil || p.IsEmpty() {
+		return nil, nil, nil, ErrEmptyPolygon
+	}
+	covering := s2.CellUnion(CoverPolygonIDs(p, maxLevel, minLevel))
+	tokens, s2cells := CellUnionTokens(covering)
+	return covering, tokens, s2cells, nil
+}
 
-		tokens = append(tokens, c.ToToken())
+// CoverPolygonWith covers the polygon with the caller configured coverer and returns the respective cells,
+// failing for empty polygons
+func CoverPolygonWith(p *s2.Polygon, rc *s2.RegionCoverer) (s2.CellUnion, []string, [][][]float64, error) {
+	if p == nil || p.IsEmpty() {
+		return nil, nil, nil, ErrEmptyPolygon
 	}
+	covering := rc.Covering(s2.Region(p))
+	tokens, s2cells := CellUnionTokens(covering)
+	return covering, tokens, s2cells, nil
+}
+
+// polygonOverlap is the region where two polygons overlap. Cells count as intersecting it when they
+// intersect both polygons, which is conservative as a region covering requires.
+type polygonOverlap struct {
+	a, b *s2.Polygon
+}
+
+func (o polygonOverlap) CapBound() s2.Cap {
+	if o.a.CapBound().Radius() < o.b.CapBound().Radius() {
+		return o.a.CapBound()
+	}
+	return o.b.CapBound()
+}
+
+func (o polygonOverlap) RectBound() s2.Rect {
+	return o.a.RectBound().Intersection(o.b.RectBound())
+}
+
+func (o polygonOverlap) ContainsCell(c s2.Cell) bool {
+	return o.a.ContainsCell(c) && o.b.ContainsCell(c)
+}
+
+func (o polygonOverlap) IntersectsCell(c s2.Cell) bool {
+	return o.a.IntersectsCell(c) && o.b.IntersectsCell(c)
+}
+
+func (o polygonOverlap) ContainsPoint(p s2.Point) bool {
+	return o.a.ContainsPoint(p) && o.b.ContainsPoint(p)
+}
+
+func (o polygonOverlap) CellUnionBound() []s2.CellID {
+	if o.a.CapBound().Radius() < o.b.CapBound().Radius() {
+		return o.a.CellUnionBound()
+	}
+	return o.b.CellUnionBound()
+}
+
+// CoverOverlap covers the region where the two polygons overlap without computing its geometry, an empty
+// covering when they do not intersect. The cells along the overlap boundary intersect both polygons but not
+// necessarily their overlap, so the covering is only as tight as the max level.
+func CoverOverlap(a, b *s2.Polygon, maxLevel, minLevel int) s2.CellUnion {
+	if a == nil || b == nil || !a.Intersects(b) {
+		return s2.CellUnion{}
+	}
+	return newCoverer(maxLevel, minLevel, maxCells).Covering(polygonOverlap{a, b})
+}
+
+// VerifyCovering drops the cells of the covering not intersecting the polygon, returning the kept cells and
+// the number of dropped ones, a safeguard against coverings broader than the polygon
+func VerifyCovering(p *s2.Polygon, cu s2.CellUnion) (s2.CellUnion, int) {
+	kept := s2.CellUnion{}
+	for _, id := range cu {
+		if p.IntersectsCell(s2.CellFromCellID(id)) {
+			kept = append(kept, id)
+		}
+	}
+	return kept, len(cu) - len(kept)
+}
+
+// CoverPolygonIDs converts s2 polygon to the cell ids of its covering like CoverPolygon without computing the
+// tokens and cells
+func CoverPolygonIDs(p *s2.Polygon, maxLevel, minLevel int) []s2.CellID {
+	rc := newCoverer(maxLevel, minLevel, maxCells)
+	return rc.Covering(s2.Region(p))
+}
+
+// CoverPolygonUniform covers the polygon with the cells of exactly the level intersecting it, a single
+// resolution grid. It fails with ErrTooManyCells when the polygon area divided by the average cell area of
+// the level is over MaxUniformCells, as the covering is not bounded by a max number of cells.
+func CoverPolygonUniform(p *s2.Polygon, level int) (s2.CellUnion, error) {
+	if UniformCellCount(p, level) > MaxUniformCells {
+		return nil, ErrTooManyCells
+	}
+	rc := newCoverer(level, level, maxCells)
+	return rc.Covering(s2.Region(p)), nil
+}
+
+// UniformCellCount estimates the number of cells of the uniform covering of the polygon at the level, the
+// polygon area divided by the average cell area of the level
+func UniformCellCount(p *s2.Polygon, level int) float64 {
+	r := EarthRadius * 1000
+	return PolygonArea(p) / (s2.AvgAreaMetric.Value(level) * r * r)
+}
+
+// CoverBorderBand covers the band of the polygon within widthMeters of its boundary, the region between the
+// boundary and an inward buffer of the polygon. Cells of the polygon covering crossing either edge of the band
+// are subdivided up to the max level, where they are kept, so the band is only as accurate as the max level.
+// As each visited cell is checked against every polygon edge, it fails with ErrTooManyCells once more than
+// MaxBandCells cells are visited, as for long boundaries at fine levels.
+func CoverBorderBand(p *s2.Polygon, widthMeters float64, maxLevel, minLevel int) (s2.CellUnion, error) {
+	rc := newCoverer(maxLevel, minLevel, maxCells)
+	width := widthMeters / 1000 / EarthRadius
+
+	band := s2.CellUnion{}
+	visited := 0
+	var visit func(id s2.CellID)
+	visit = func(id s2.CellID) {
+		if visited++; visited > MaxBandCells {
+			return
+		}
+		cell := s2.CellFromCellID(id)
+		d := shapeDistanceMeters(p, id.Point()) / 1000 / EarthRadius
+		r := cell.CapBound().Radius().Radians()
+		switch {
+		case d-r > width:
+			// the cell is farther than the width from the boundary, inside or outside of the polygon
+			return
+		case d+r <= width && p.ContainsCell(cell) || id.Level() >= maxLevel:
+			band = append(band, id)
+			return
+		}
+		for _, ch := range id.Children() {
+			if p.IntersectsCell(s2.CellFromCellID(ch)) {
+				visit(ch)
+			}
+		}
+	}
+	for _, id := range rc.Covering(p) {
+		visit(id)
+	}
+	if visited > MaxBandCells {
+		return nil, ErrTooManyCells
+	}
+	band.Normalize()
+	return band, nil
+}
+
+// BBoxToRect converts a bounding box in degrees to s2 rect, a min lng greater than the max lng crosses the antimeridian
+func BBoxToRect(minLat, minLng, maxLat, maxLng float64) (s2.Rect, error) {
+	if minLat > maxLat {
+		return s2.EmptyRect(), errors.New("bbox min lat greater than max lat")
+	}
+	r := s2.Rect{
+		Lat: r1.Interval{Lo: (s1.Angle(minLat) * s1.Degree).Radians(), Hi: (s1.Angle(maxLat) * s1.Degree).Radians()},
+		Lng: s1.IntervalFromEndpoints((s1.Angle(minLng) * s1.Degree).Radians(), (s1.Angle(maxLng) * s1.Degree).Radians()),
+	}
+	if !r.IsValid() {
+		return s2.EmptyRect(), errors.New("bbox out of range")
+	}
+	return r, nil
+}
+
+// FeatureRect returns the bounding rect of the feature, given by the bbox of the feature or of its geometry
+// or else computed from the polygon rings, lines and points of the geometry, skipping invalid positions
+func FeatureRect(f *geojson.Feature) s2.Rect {
+	for _, bbox := range [][]float64{f.BoundingBox, geometryBBox(f.Geometry)} {
+		if r, err := bboxToRect(bbox); err == nil {
+			return r
+		}
+	}
+
+	r := s2.EmptyRect()
+	add := func(positions [][]float64) {
+		for _, pt := range positions {
+			if len(pt) >= 2 {
+				r = r.AddPoint(s2.LatLngFromDegrees(pt[1], pt[0]))
+			}
+		}
+	}
+	for _, ring := range PolygonRings(f.Geometry) {
+		add(ring)
+	}
+	for _, line := range LineStrings(f.Geometry) {
+		add(line)
+	}
+	add(PointPositions(f.Geometry))
+	return r
+}
+
+// MinimalEnclosingCell returns the smallest cell containing the polygon and line vertices and the points of the
+// geometry, as cells are convex it contains the regions outlined by the polygons and the lines as well.
+// It returns the invalid zero cell id for empty geometries and geometries spanning several cube faces.
+func MinimalEnclosingCell(g *geojson.Geometry) s2.CellID {
+	positions := PointPositions(g)
+	for _, ring := range PolygonRings(g) {
+		positions = append(positions, ring...)
+	}
+	for _, line := range LineStrings(g) {
+		positions = append(positions, line...)
+	}
+
+	var cell s2.CellID
+	for _, pt := range positions {
+		if len(pt) < 2 {
+			continue
+		}
+		id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0]))
+		if cell == 0 {
+			cell = id
+			continue
+		}
+		level, ok := cell.CommonAncestorLevel(id)
+		if !ok {
+			return 0
+		}
+		cell = cell.Parent(level)
+	}
+	return cell
+}
+
+func geometryBBox(g *geojson.Geometry) []float64 {
+	if g == nil {
+		return nil
+	}
+	return g.BoundingBox
+}
+
+// bboxToRect converts a geojson bbox of 2 or 3 dimensions, west, south, east, north, to s2 rect
+func bboxToRect(bbox []float64) (s2.Rect, error) {
+	switch len(bbox) {
+	case 4:
+		return BBoxToRect(bbox[1], bbox[0], bbox[3], bbox[2])
+	case 6:
+		return BBoxToRect(bbox[1], bbox[0], bbox[4], bbox[3])
+	}
+	return s2.EmptyRect(), errors.New("bbox must have 4 or 6 values")
+}
+
+// CoverRect converts s2 rect to cell union and returns the respective cells
+func CoverRect(r s2.Rect, maxLevel, minLevel int) (s2.CellUnion, []string, [][][]float64) {
+	rc := newCoverer(maxLevel, minLevel, maxCells)
+	covering := rc.Covering(r)
+
+	tokens, s2cells := CellUnionTokens(covering)
 	return covering, tokens, s2cells
 }
 
+// CoverComplement covers the part of the rect outside the polygons, the cells of the rect covering minus the
+// interior coverings of the polygons. Its accuracy along the polygon edges depends on the max level.
+func CoverComplement(r s2.Rect, polygons []*s2.Polygon, maxLevel, minLevel int) s2.CellUnion {
+	rc := newCoverer(maxLevel, minLevel, maxCells)
+	var interiors []s2.CellUnion
+	for _, p := range polygons {
+		interiors = append(interiors, rc.InteriorCovering(p))
+	}
+	return s2.CellUnionFromDifference(rc.Covering(r), s2.CellUnionFromUnion(interiors...))
+}
+
+// CoverCircle covers the cap of radiusMeters around the center with at most maxCells cells up to the max level
+func CoverCircle(center Point, radiusMeters float64, maxLevel, maxCells int) s2.CellUnion {
+	c := s2.PointFromLatLng(s2.LatLngFromDegrees(center.Lat, center.Lng))
+	ca := s2.CapFromCenterAngle(c, s1.Angle(radiusMeters/1000/EarthRadius))
+	return newCoverer(maxLevel, 0, maxCells).Covering(ca)
+}
+
+// CoverAnnulus covers the ring between the inner and outer radii in meters around the center, the cells of
+// the outer cap covering minus the interior covering of the inner cap. The cells along the inner circle are
+// kept, so its accuracy there depends on the max level as for CoverComplement.
+func CoverAnnulus(center Point, innerMeters, outerMeters float64, maxLevel, minLevel int) s2.CellUnion {
+	c := s2.PointFromLatLng(s2.LatLngFromDegrees(center.Lat, center.Lng))
+	inner := s2.CapFromCenterAngle(c, s1.Angle(innerMeters/1000/EarthRadius))
+	outer := s2.CapFromCenterAngle(c, s1.Angle(outerMeters/1000/EarthRadius))
+	rc := newCoverer(maxLevel, minLevel, maxCells)
+	return s2.CellUnionFromDifference(rc.Covering(outer), rc.InteriorCovering(inner))
+}
+
+// ClipCovering returns the part of the covering within the rect, the intersection with the rect covering at
+// the levels. The cells along the rect edges reach outside it by at most a cell of the rect covering.
+func ClipCovering(cu s2.CellUnion, r s2.Rect, maxLevel, minLevel int) s2.CellUnion {
+	return s2.CellUnionFromIntersection(s2.CellUnionFromUnion(cu), newCoverer(maxLevel, minLevel, maxCells).Covering(r))
+}
+
+// CellUnionToTokens returns the tokens of the cells of a cell union
+func CellUnionToTokens(cu s2.CellUnion) []string {
+	tokens := make([]string, len(cu))
+	for i, id := range cu {
+		tokens[i] = id.ToToken()
+	}
+	return tokens
+}
+
+// CellUnionTokens returns the tokens and the edges of the cells of a cell union
+func CellUnionTokens(cu s2.CellUnion) ([]string, [][][]float64) {
+	var tokens []string
+	var s2cells [][][]float64
+
+	for _, id := range cu {
+		s2cells = append(s2cells, EdgesOfCell(s2.CellFromCellID(id)))
+		tokens = append(tokens, id.ToToken())
+	}
+	return tokens, s2cells
+}
+
+// CirclePolygon returns the closed counterclockwise ring of [lng, lat] positions of segments vertices at the
+// geodesic distance radiusMeters from the center, the first vertex due north of it
+func CirclePolygon(center Point, radiusMeters float64, segments int) [][]float64 {
+	lat, lng := center.Lat*math.Pi/180, center.Lng*math.Pi/180
+	d := radiusMeters / 1000 / EarthRadius
+	ring := make([][]float64, 0, segments+1)
+	for i := 0; i < segments; i++ {
+		// decreasing bearings from north wind the ring counterclockwise
+		bearing := -2 * math.Pi * float64(i) / float64(segments)
+		vlat := math.Asin(math.Sin(lat)*math.Cos(d) + math.Cos(lat)*math.Sin(d)*math.Cos(bearing))
+		vlng := lng + math.Atan2(math.Sin(bearing)*math.Sin(d)*math.Cos(lat), math.Cos(d)-math.Sin(lat)*math.Sin(vlat))
+		ring = append(ring, []float64{math.Remainder(vlng*180/math.Pi, 360), vlat * 180 / math.Pi})
+	}
+	if len(ring) > 0 {
+		ring = append(ring, ring[0])
+	}
+	return ring
+}
+
+// PointCellID returns the id of the cell of the level containing the point
+func PointCellID(p Point, level int) s2.CellID {
+	return s2.CellIDFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lng)).Parent(level)
+}
+
 // CoverPoint converts a point to cell based on given level
 func CoverPoint(p Point, maxLevel int) (s2.Cell, string, [][][]float64) {
 	var s2cells [][][]float64
 
-	cid := s2.CellFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lng)).ID().Parent(maxLevel)
+	cid := PointCellID(p, maxLevel)
 	cell := s2.CellFromCellID(cid)
 	token := cid.ToToken()
 
@@ -69,6 +837,79 @@ func CoverPoint(p Point, maxLevel int) (s2.Cell, string, [][][]float64) {
 	return cell, token, s2cells
 }
 
+// CoveringStats computes the level statistics of the cells of a covering
+func CoveringStats(cu s2.CellUnion) LevelStats {
+	var stats LevelStats
+	if len(cu) == 0 {
+		return stats
+	}
+	sum := 0
+	stats.MinLevel = cu[0].Level()
+	for _, id := range cu {
+		l := id.Level()
+		if l < stats.MinLevel {
+			stats.MinLevel = l
+		}
+		if l > stats.MaxLevel {
+			stats.MaxLevel = l
+		}
+		sum += l
+	}
+	stats.CellCount = len(cu)
+	stats.AvgLevel = float64(sum) / float64(len(cu))
+	return stats
+}
+
+// PolygonArea returns the area of the polygon in square meters
+func PolygonArea(p *s2.Polygon) float64 {
+	r := EarthRadius * 1000
+	return p.Area() * r * r
+}
+
+// CoverageArea returns the area of the cells of the union in square meters, counting overlapping cells once
+func CoverageArea(cu s2.CellUnion) float64 {
+	r := EarthRadius * 1000
+	cu = s2.CellUnionFromUnion(cu)
+	return cu.ExactArea() * r * r
+}
+
+// IsGlobalCovering checks if the covering essentially covers the planet, which is the case for
+// inverted or degenerate polygons. It reports true when the covering contains all six face cells,
+// or when it covers more than a hemisphere since the coverer leaves a gap around an inverted polygon.
+func IsGlobalCovering(cu s2.CellUnion) bool {
+	cu = s2.CellUnionFromUnion(cu)
+	faces := 0
+	for f := 0; f < 6; f++ {
+		if cu.ContainsCellID(s2.CellIDFromFace(f)) {
+			faces++
+		}
+	}
+	return faces == 6 || cu.ApproxArea() > 2*math.Pi
+}
+
+// CellEdgeLength returns the average edge length in meters of the cells at the given level
+func CellEdgeLength(level int) float64 {
+	return s2.AvgEdgeMetric.Value(level) * EarthRadius * 1000
+}
+
+// LevelForCellSize returns the level whose average cell edge length is closest to the meters, closest by
+// ratio as the edges halve from level to level, e.g. 14 for about 500m cells
+func LevelForCellSize(meters float64) int {
+	return s2.AvgEdgeMetric.ClosestLevel(meters / 1000 / EarthRadius)
+}
+
+// CellIDToPath returns the face and the child positions from the face down to the cell, e.g. "2/013201"
+func CellIDToPath(id s2.CellID) string {
+	if !id.IsValid() {
+		return ""
+	}
+	path := []byte{byte('0' + id.Face()), '/'}
+	for level := 1; level <= id.Level(); level++ {
+		path = append(path, byte('0'+id.ChildPosition(level)))
+	}
+	return string(path)
+}
+
 // EdgesOfCell gets the edges of the cell
 func EdgesOfCell(c s2.Cell) [][]float64 {
 	var edges [][]float64
@@ -78,3 +919,80 @@ func EdgesOfCell(c s2.Cell) [][]float64 {
 	}
 	return edges
 }
+
+// CellBounds is the lat/lng bounding rectangle of a cell in degrees
+type CellBounds struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+// BoundsOfCell gets the RectBound of the cell. As the cell edges are geodesics the rect slightly overstates
+// the cell, the most near the poles.
+func BoundsOfCell(c s2.Cell) CellBounds {
+	r := c.RectBound()
+	return CellBounds{
+		MinLat: r.Lo().Lat.Degrees(),
+		MinLng: r.Lo().Lng.Degrees(),
+		MaxLat: r.Hi().Lat.Degrees(),
+		MaxLng: r.Hi().Lng.Degrees(),
+	}
+}
+
+// CellUnionBounds returns the bounds of the cells of a cell union
+func CellUnionBounds(cu s2.CellUnion) []CellBounds {
+	bounds := make([]CellBounds, len(cu))
+	for i, id := range cu {
+		bounds[i] = BoundsOfCell(s2.CellFromCellID(id))
+	}
+	return bounds
+}
+
+// LngLatCells returns the cells with the vertices in the GeoJSON lng,lat order instead of the lat,lng
+// order of EdgesOfCell
+func LngLatCells(cells [][][]float64) [][][]float64 {
+	swapped := make([][][]float64, len(cells))
+	for i, cell := range cells {
+		swapped[i] = make([][]float64, len(cell))
+		for j, v := range cell {
+			swapped[i][j] = []float64{v[1], v[0]}
+		}
+	}
+	return swapped
+}
+
+// ReprojectWGS84ToMercator reprojects the lng,lat vertices of the cells to web mercator x,y meters,
+// latitudes beyond the edges of the web mercator world are clamped
+func ReprojectWGS84ToMercator(cells [][][]float64) [][][]float64 {
+	const r = 6378137
+	projected := make([][][]float64, len(cells))
+	for i, cell := range cells {
+		projected[i] = make([][]float64, len(cell))
+		for j, v := range cell {
+			lat := math.Max(-mvtMaxLat, math.Min(mvtMaxLat, v[1])) * math.Pi / 180
+			projected[i][j] = []float64{r * v[0] * math.Pi / 180, r * math.Log(math.Tan(math.Pi/4+lat/2))}
+		}
+	}
+	return projected
+}
+
+// RoundCells rounds the vertex coordinates of the cells to the decimals, so that they marshal to
+// short numbers. Negative decimals keep the full precision.
+func RoundCells(cells [][][]float64, decimals int) [][][]float64 {
+	if decimals < 0 {
+		return cells
+	}
+	scale := math.Pow(10, float64(decimals))
+	rounded := make([][][]float64, len(cells))
+	for i, cell := range cells {
+		rounded[i] = make([][]float64, len(cell))
+		for j, v := range cell {
+			rounded[i][j] = make([]float64, len(v))
+			for k, x := range v {
+				rounded[i][j][k] = math.Round(x*scale) / scale
+			}
+		}
+	}
+	return rounded
+}
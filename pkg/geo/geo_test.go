@@ -1,7 +1,10 @@
 package geo
 
 import (
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -54,19 +57,124 @@ func TestPointsToPolygon(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, r[0].Geometry.IsPolygon())
 
-	p := PointsToPolygon(r[0].Geometry.Polygon[0])
+	p, err := PointsToPolygon(r[0].Geometry.Polygon[0])
+	assert.NoError(t, err)
 	assert.Equal(t, 4, p.NumEdges())
+
+	_, err = PointsToPolygon(nil)
+	assert.Equal(t, ErrEmptyRing, err)
+	_, err = PointsToPolygon([][]float64{{1, 1}})
+	assert.Equal(t, ErrDegenerateRing, err)
+	_, err = PointsToPolygon([][]float64{{0, 0}, {1, 1}, {0, 0}})
+	assert.Equal(t, ErrDegenerateRing, err)
+	_, err = PointsToPolygon([][]float64{{0, 0}, {1}, {1, 1}, {0, 0}})
+	assert.Error(t, err)
+}
+
+func TestPointsToPolygonDegenerate(t *testing.T) {
+	// two point rings, open and closed
+	_, err := PointsToPolygon([][]float64{{0, 0}, {1, 1}})
+	assert.Equal(t, ErrDegenerateRing, err)
+	_, err = PointsToPolygon([][]float64{{0, 0}, {1, 1}, {0, 0}})
+	assert.Equal(t, ErrDegenerateRing, err)
+
+	// repeated positions do not count as vertices
+	_, err = PointsToPolygon([][]float64{{0, 0}, {0, 0}, {1, 1}, {0, 0}})
+	assert.Equal(t, ErrDegenerateRing, err)
+	_, err = PointsToPolygon([][]float64{{0, 0}, {1, 1}, {0, 0}, {1, 1}})
+	assert.Equal(t, ErrDegenerateRing, err)
+
+	// three distinct points make a triangle, closed or not
+	p, err := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}})
+	assert.NoError(t, err)
+	assert.NoError(t, p.Validate())
+	p, err = PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 0}})
+	assert.NoError(t, err)
+	assert.True(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(0.2, 0.8))))
+}
+
+func TestPointsToPolygonSnap(t *testing.T) {
+	ring := [][]float64{{0, 0}, {1, 0}, {1.000000001, 0.000000001}, {1, 1}, {0, 1}, {0, 0}}
+	p, err := PointsToPolygon(ring)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, p.NumEdges())
+
+	p, err = PointsToPolygonSnapped(ring, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, p.NumEdges())
+	p, err = PointsToPolygonSnapped(ring, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, p.NumEdges())
+
+	_, err = PointsToPolygonSnapped([][]float64{{0, 0}, {1}, {1, 1}, {0, 0}}, 7)
+	assert.EqualError(t, err, "invalid coordinate in ring")
+	// snapping can leave too few vertices
+	_, err = PointsToPolygonSnapped([][]float64{{0, 0}, {0.00000001, 0}, {0, 0.00000001}, {0, 0}}, 7)
+	assert.Equal(t, ErrDegenerateRing, err)
 }
 
 func TestCoverPolygon(t *testing.T) {
 	f, _ := DecodeGeoJSON(validJSON)
-	p := PointsToPolygon(f[0].Geometry.Polygon[0])
+	p, _ := PointsToPolygon(f[0].Geometry.Polygon[0])
 
-	u, tk, c := CoverPolygon(p, 4, 1)
+	u, tk, c, err := CoverPolygon(p, 4, 1)
+	assert.NoError(t, err)
 	assert.True(t, u.IsValid())
 	assert.Equal(t, 22, len(tk))
 	assert.Equal(t, 4, len(c[0]))
 
+	_, _, _, err = CoverPolygon(s2.PolygonFromLoops([]*s2.Loop{s2.EmptyLoop()}), 4, 1)
+	assert.Equal(t, ErrEmptyPolygon, err)
+	_, _, _, err = CoverPolygon(nil, 4, 1)
+	assert.Equal(t, ErrEmptyPolygon, err)
+
+}
+
+func TestCoverPolygonGolden(t *testing.T) {
+	// pins the covering so that changes of the s2 library defaults show up here
+	p, _ := PointsToPolygon([][]float64{{10, 40}, {11, 40}, {11, 41}, {10, 41}, {10, 40}})
+	_, tk, _, err := CoverPolygon(p, 9, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"12d8b", "12d8d", "12df4", "12df84", "12df94", "12df9c", "12dfb", "13205c", "132064", "13207c",
+		"13209", "1320b", "1320c4", "1320cc", "1320ec", "1320f4", "132734", "13273c", "13275",
+	}, tk)
+
+	tk, _ = CellUnionTokens(CoverCircle(Point{Lat: 40.5, Lng: 10.5}, 20000, 9, 8))
+	assert.Equal(t, []string{"12df5", "12df64", "12df6c", "12df7c", "13209c", "1320a4", "1320ac"}, tk)
+}
+
+func TestVerifyCovering(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{10, 40}, {11, 40}, {11, 41}, {10, 41}, {10, 40}})
+	covering, _, _, _ := CoverPolygon(p, 9, 1)
+	kept, dropped := VerifyCovering(p, covering)
+	assert.Equal(t, covering, kept)
+	assert.Equal(t, 0, dropped)
+
+	far := s2.CellIDFromLatLng(s2.LatLngFromDegrees(-40, -10)).Parent(9)
+	kept, dropped = VerifyCovering(p, append(s2.CellUnion{far}, covering...))
+	assert.Equal(t, covering, kept)
+	assert.Equal(t, 1, dropped)
+}
+
+func TestCoverPolygonWith(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	p, _ := PointsToPolygon(f[0].Geometry.Polygon[0])
+
+	u, tk, c, err := CoverPolygonWith(p, &s2.RegionCoverer{MinLevel: 6, MaxLevel: 8, LevelMod: 2, MaxCells: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, len(u), len(tk))
+	assert.Equal(t, len(u), len(c))
+	for _, id := range u {
+		assert.Contains(t, []int{6, 8}, id.Level())
+	}
+
+	want, _, _, _ := CoverPolygon(p, 4, 1)
+	u, _, _, _ = CoverPolygonWith(p, &s2.RegionCoverer{MinLevel: 1, MaxLevel: 4, MaxCells: maxCells})
+	assert.Equal(t, want, u)
+
+	_, _, _, err = CoverPolygonWith(nil, &s2.RegionCoverer{})
+	assert.Equal(t, ErrEmptyPolygon, err)
 }
 
 func TestCoverPoint(t *testing.T) {
@@ -76,3 +184,625 @@ func TestCoverPoint(t *testing.T) {
 	assert.Equal(t, 4, len(edges[0]))
 
 }
+
+func TestPolygonRings(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	assert.Equal(t, f[0].Geometry.Polygon, PolygonRings(f[0].Geometry))
+	assert.Nil(t, PointPositions(f[0].Geometry))
+
+	g, _ := DecodeWKT("MULTIPOLYGON (((30 20, 45 40, 10 40, 30 20)), ((15 5, 40 10, 10 20, 5 10, 15 5)))")
+	assert.Equal(t, 2, len(PolygonRings(g)))
+
+	g, _ = DecodeWKT("MULTIPOINT (10 40, 40 30)")
+	assert.Equal(t, [][]float64{{10, 40}, {40, 30}}, PointPositions(g))
+	assert.Nil(t, PolygonRings(g))
+}
+
+func TestLineStrings(t *testing.T) {
+	g := geojson.NewLineStringGeometry([][]float64{{0, 0}, {1, 1}})
+	assert.Equal(t, [][][]float64{{{0, 0}, {1, 1}}}, LineStrings(g))
+
+	g = geojson.NewMultiLineStringGeometry([][]float64{{0, 0}, {1, 1}}, [][]float64{{2, 2}, {3, 3}})
+	assert.Equal(t, 2, len(LineStrings(g)))
+
+	assert.Nil(t, LineStrings(geojson.NewPointGeometry([]float64{0, 0})))
+	assert.Nil(t, LineStrings(nil))
+}
+
+func TestPolygonsContainPoint(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	polygons := FeaturePolygons(f)
+	assert.Equal(t, 1, len(polygons))
+
+	assert.True(t, PolygonsContainPoint(polygons, Point{Lat: 39.5, Lng: -90.5}))
+	assert.False(t, PolygonsContainPoint(polygons, Point{Lat: 35.5666, Lng: 23.4444}))
+}
+
+func TestPolygonsContainPointHoles(t *testing.T) {
+	// a square with a clockwise hole, and a multipolygon with a second holed square
+	hole := [][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}},
+	}
+	polygons := FeaturePolygons([]*geojson.Feature{
+		geojson.NewPolygonFeature(hole),
+		geojson.NewMultiPolygonFeature([][][]float64{{{20, 20}, {21, 20}, {21, 21}, {20, 21}, {20, 20}}}, hole),
+	})
+	assert.Equal(t, 3, len(polygons))
+	assert.Equal(t, 2, polygons[0].NumLoops())
+
+	assert.True(t, PolygonsContainPoint(polygons, Point{Lat: 2, Lng: 2}))
+	assert.True(t, PolygonsContainPoint(polygons, Point{Lat: 20.5, Lng: 20.5}))
+	assert.False(t, PolygonsContainPoint(polygons, Point{Lat: 5, Lng: 5}))
+	assert.False(t, PolygonsContainPoint(polygons, Point{Lat: 50, Lng: 100}))
+
+	// polygons with degenerate rings are skipped
+	assert.Empty(t, FeaturePolygons([]*geojson.Feature{geojson.NewPolygonFeature([][][]float64{hole[0], {{4, 4}, {5, 5}, {4, 4}}})}))
+}
+
+func TestFixRingWinding(t *testing.T) {
+	ccw := [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	cw := [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}
+	assert.Equal(t, ccw, FixRingWinding(ccw))
+	assert.Equal(t, ccw[:4], FixRingWinding(ccw[:4]))
+	assert.Equal(t, [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}, FixRingWinding(cw))
+	assert.Equal(t, [][]float64{{1, 0}, {1, 1}, {0, 1}, {0, 0}}, FixRingWinding(cw[:4]))
+	assert.Equal(t, [][]float64{{0, 0}, {1, 1}, {0, 0}}, FixRingWinding([][]float64{{0, 0}, {1, 1}, {0, 0}}))
+
+	p, err := PointsToPolygon(FixRingWinding(cw))
+	assert.NoError(t, err)
+	assert.True(t, p.Area() < 0.001)
+}
+
+func TestMinimalEnclosingCell(t *testing.T) {
+	square := geojson.NewPolygonGeometry([][][]float64{{{10, 10}, {10.01, 10}, {10.01, 10.01}, {10, 10.01}, {10, 10}}})
+	id := MinimalEnclosingCell(square)
+	assert.True(t, id.IsValid())
+	cell := s2.CellFromCellID(id)
+	for _, pt := range square.Polygon[0] {
+		assert.True(t, cell.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0]))))
+	}
+	// no child contains all the vertices
+	for _, child := range id.Children() {
+		contained := 0
+		for _, pt := range square.Polygon[0] {
+			if child.Contains(s2.CellIDFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0]))) {
+				contained++
+			}
+		}
+		assert.True(t, contained < 5)
+	}
+
+	pt := s2.CellIDFromLatLng(s2.LatLngFromDegrees(1, 2))
+	assert.Equal(t, pt, MinimalEnclosingCell(geojson.NewPointGeometry([]float64{2, 1})))
+	assert.Equal(t, s2.CellIDFromFace(pt.Face()), MinimalEnclosingCell(geojson.NewLineStringGeometry([][]float64{{2, 1}, {30, -30}})))
+	assert.Equal(t, s2.CellID(0), MinimalEnclosingCell(geojson.NewMultiPointGeometry([]float64{0, 0}, []float64{180, 0})))
+	assert.Equal(t, s2.CellID(0), MinimalEnclosingCell(geojson.NewMultiPointGeometry()))
+}
+
+func TestPointsOutside(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	polygons := FeaturePolygons(f)
+
+	points := []Point{{Lat: 39.5, Lng: -90.5}, {Lat: 35.5666, Lng: 23.4444}, {Lat: 39.5, Lng: -90.5}, {Lat: 0, Lng: 0}}
+	assert.Equal(t, []int{1, 3}, PointsOutside(polygons, points))
+	assert.Equal(t, []int{}, PointsOutside(polygons, points[:1]))
+	assert.Equal(t, []int{0}, PointsOutside(nil, points[:1]))
+
+	fence := FeaturePolygons([]*geojson.Feature{geojson.NewPolygonFeature([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}},
+	})})
+	assert.Equal(t, []int{1, 2}, PointsOutside(fence, []Point{{Lat: 2, Lng: 2}, {Lat: 5, Lng: 5}, {Lat: 50, Lng: 100}}))
+}
+
+func TestRepairPolygon(t *testing.T) {
+	// open, clockwise ring with a duplicate vertex and a spike
+	ring := [][]float64{{0, 0}, {0, 1}, {0, 1}, {1, 1}, {2, 2}, {1, 1}, {1, 0}}
+	r, err := RepairPolygon(ring)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{1, 0}, {1, 1}, {0, 1}, {0, 0}, {1, 0}}, r)
+
+	_, err = RepairPolygon([][]float64{{0, 0}, {1, 1}, {0, 0}})
+	assert.Error(t, err)
+}
+
+func TestCellEdgeLength(t *testing.T) {
+	assert.InDelta(t, 9296665, CellEdgeLength(0), 1)
+	assert.InDelta(t, 567, CellEdgeLength(14), 1)
+	assert.True(t, CellEdgeLength(10) > CellEdgeLength(11))
+}
+
+func TestLevelForCellSize(t *testing.T) {
+	assert.Equal(t, 14, LevelForCellSize(500))
+	for level := 0; level <= 30; level++ {
+		assert.Equal(t, level, LevelForCellSize(CellEdgeLength(level)))
+		assert.Equal(t, level, LevelForCellSize(CellEdgeLength(level)*1.3))
+		assert.Equal(t, level, LevelForCellSize(CellEdgeLength(level)*0.75))
+	}
+	assert.Equal(t, 0, LevelForCellSize(1e9))
+	assert.Equal(t, 30, LevelForCellSize(0))
+}
+
+func TestCoveringStats(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	p, _ := PointsToPolygon(f[0].Geometry.Polygon[0])
+	u, _, _, _ := CoverPolygon(p, 4, 1)
+
+	stats := CoveringStats(u)
+	assert.Equal(t, 22, stats.CellCount)
+	assert.True(t, stats.MinLevel >= 1)
+	assert.True(t, stats.MaxLevel <= 4)
+	assert.True(t, stats.AvgLevel >= float64(stats.MinLevel) && stats.AvgLevel <= float64(stats.MaxLevel))
+
+	assert.Equal(t, LevelStats{}, CoveringStats(nil))
+}
+
+func TestPolygonToGeoJSON(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	p, _ := PointsToPolygon(f[0].Geometry.Polygon[0])
+
+	g := PolygonToGeoJSON(p)
+	assert.True(t, g.IsPolygon())
+	assert.Equal(t, 1, len(g.Polygon))
+	assert.Equal(t, 5, len(g.Polygon[0]))
+	assert.InDeltaSlice(t, f[0].Geometry.Polygon[0][0], g.Polygon[0][0], 1e-9)
+	assert.Equal(t, g.Polygon[0][0], g.Polygon[0][4])
+
+	shell := s2.LoopFromPoints([]s2.Point{
+		s2.PointFromLatLng(s2.LatLngFromDegrees(0, 0)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(0, 10)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(10, 10)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(10, 0)),
+	})
+	hole := s2.LoopFromPoints([]s2.Point{
+		s2.PointFromLatLng(s2.LatLngFromDegrees(2, 2)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(2, 8)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(8, 8)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(8, 2)),
+	})
+	island := s2.LoopFromPoints([]s2.Point{
+		s2.PointFromLatLng(s2.LatLngFromDegrees(20, 20)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(20, 22)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(22, 22)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(22, 20)),
+	})
+	g = PolygonToGeoJSON(s2.PolygonFromLoops([]*s2.Loop{shell, hole, island}))
+	assert.True(t, g.IsMultiPolygon())
+	assert.Equal(t, 2, len(g.MultiPolygon))
+	assert.Equal(t, 3, len(g.MultiPolygon[0])+len(g.MultiPolygon[1]))
+}
+
+func TestEnforceGeoJSONWinding(t *testing.T) {
+	f := geojson.NewPolygonFeature([][][]float64{
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{2, 2}, {8, 2}, {8, 8}, {2, 8}, {2, 2}},
+	})
+	EnforceGeoJSONWinding(f)
+	assert.Equal(t, [][]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}, f.Geometry.Polygon[0])
+	assert.Equal(t, [][]float64{{2, 2}, {2, 8}, {8, 8}, {8, 2}, {2, 2}}, f.Geometry.Polygon[1])
+
+	m := geojson.NewMultiPolygonFeature([][][]float64{{{0, 0}, {0, 1}, {1, 1}, {0, 0}}})
+	EnforceGeoJSONWinding(m)
+	assert.Equal(t, [][]float64{{0, 0}, {1, 1}, {0, 1}, {0, 0}}, m.Geometry.MultiPolygon[0][0])
+}
+
+func TestFeatureContainsPoint(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	assert.True(t, FeatureContainsPoint(f[0], Point{Lat: 39.5, Lng: -90.5}))
+	assert.False(t, FeatureContainsPoint(f[0], Point{Lat: 35.5666, Lng: 23.4444}))
+
+	zone := geojson.NewPolygonFeature([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}},
+	})
+	assert.True(t, FeatureContainsPoint(zone, Point{Lat: 2, Lng: 2}))
+	assert.False(t, FeatureContainsPoint(zone, Point{Lat: 5, Lng: 5}))
+	assert.False(t, FeatureContainsPoint(zone, Point{Lat: 50, Lng: 100}))
+}
+
+func TestDensifyPolygon(t *testing.T) {
+	ring := [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 0}}
+	assert.Equal(t, ring, DensifyPolygon(ring, 0))
+	assert.Equal(t, ring, DensifyPolygon(ring, 1000000))
+
+	// one degree at the equator is about 111km
+	dense := DensifyPolygon(ring, 60000)
+	assert.Equal(t, []float64{0, 0}, dense[0])
+	assert.Equal(t, []float64{0.5, 0}, dense[1])
+	assert.Equal(t, []float64{1, 0}, dense[2])
+	assert.Equal(t, []float64{0, 0}, dense[len(dense)-1])
+	assert.Equal(t, 1+2+2+3, len(dense))
+}
+
+func TestIsGlobalCovering(t *testing.T) {
+	f, _ := DecodeGeoJSON(validJSON)
+	ring := f[0].Geometry.Polygon[0]
+	p, _ := PointsToPolygon(ring)
+	u, _, _, _ := CoverPolygon(p, 4, 1)
+	assert.False(t, IsGlobalCovering(u))
+
+	var reversed [][]float64
+	for i := len(ring) - 1; i >= 0; i-- {
+		reversed = append(reversed, ring[i])
+	}
+	p, _ = PointsToPolygon(reversed)
+	u, _, _, _ = CoverPolygon(p, 4, 1)
+	assert.True(t, IsGlobalCovering(u))
+
+	var faces s2.CellUnion
+	for f := 0; f < 6; f++ {
+		children := s2.CellIDFromFace(f).Children()
+		faces = append(faces, children[:]...)
+	}
+	assert.True(t, IsGlobalCovering(faces))
+}
+
+func TestPolygonContainsPolygon(t *testing.T) {
+	outer, err := GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, outer.NumLoops())
+
+	inner, _ := GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}}}))
+	assert.True(t, PolygonContainsPolygon(outer, inner))
+	assert.False(t, PolygonContainsPolygon(inner, outer))
+
+	inHole, _ := GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{{{4.5, 4.5}, {5, 4.5}, {5, 5}, {4.5, 4.5}}}))
+	assert.False(t, PolygonContainsPolygon(outer, inHole))
+
+	overlapping, _ := GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{{{8, 8}, {12, 8}, {12, 12}, {8, 12}, {8, 8}}}))
+	assert.False(t, PolygonContainsPolygon(outer, overlapping))
+
+	_, err = GeometryToPolygon(geojson.NewPointGeometry([]float64{1, 1}))
+	assert.Error(t, err)
+}
+
+func TestGeometryToPolygonInvalidRings(t *testing.T) {
+	_, err := GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{{}}))
+	assert.Equal(t, ErrEmptyRing, err)
+	_, err = GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1, 1}, {0, 0}}}))
+	assert.Equal(t, ErrDegenerateRing, err)
+	// a degenerate hole fails the polygon as well
+	_, err = GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {4, 4}, {4, 4}},
+	}))
+	assert.Equal(t, ErrDegenerateRing, err)
+	_, err = GeometryToPolygon(geojson.NewMultiPolygonGeometry(
+		[][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}},
+		[][][]float64{{{5, 5}, {6}, {6, 6}, {5, 5}}},
+	))
+	assert.Error(t, err)
+}
+
+func TestIntersectionMatrix(t *testing.T) {
+	square := func(x, y float64) *s2.Polygon {
+		p, _ := GeometryToPolygon(geojson.NewPolygonGeometry([][][]float64{{{x, y}, {x + 2, y}, {x + 2, y + 2}, {x, y + 2}, {x, y}}}))
+		return p
+	}
+	m := IntersectionMatrix([]*s2.Polygon{square(0, 0), square(1, 1), square(10, 10), square(11, 0)})
+	assert.Equal(t, [][]bool{
+		{true, true, false, false},
+		{true, true, false, false},
+		{false, false, true, false},
+		{false, false, false, true},
+	}, m)
+	assert.Empty(t, IntersectionMatrix(nil))
+}
+
+func TestCirclePolygon(t *testing.T) {
+	center := Point{Lat: 40, Lng: 179.99}
+	ring := CirclePolygon(center, 1000, 64)
+	assert.Equal(t, 65, len(ring))
+	assert.Equal(t, ring[0], ring[64])
+	assert.InDelta(t, 179.99, ring[0][0], 1e-9)
+	assert.True(t, ring[0][1] > 40)
+
+	c := s2.LatLngFromDegrees(center.Lat, center.Lng)
+	for _, v := range ring {
+		assert.True(t, v[0] >= -180 && v[0] <= 180)
+		meters := c.Distance(s2.LatLngFromDegrees(v[1], v[0])).Radians() * EarthRadius * 1000
+		assert.InDelta(t, 1000, meters, 1e-6)
+	}
+
+	// counterclockwise, so the polygon is the small disc around the center across the antimeridian
+	p, err := PointsToPolygon(ring)
+	assert.NoError(t, err)
+	assert.True(t, p.ContainsPoint(s2.PointFromLatLng(c)))
+	assert.InDelta(t, math.Pi*1000*1000, PolygonArea(p), math.Pi*1000*1000*0.01)
+
+	assert.Empty(t, CirclePolygon(center, 1000, 0))
+}
+
+func TestCoverOverlap(t *testing.T) {
+	a, _ := PointsToPolygon([][]float64{{0, 0}, {2, 0}, {2, 2}, {0, 2}, {0, 0}})
+	b, _ := PointsToPolygon([][]float64{{1, 1}, {3, 1}, {3, 3}, {1, 3}, {1, 1}})
+	overlap := CoverOverlap(a, b, 10, 1)
+	assert.NotEmpty(t, overlap)
+	assert.True(t, overlap.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(1.5, 1.5))))
+	assert.False(t, overlap.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+	assert.False(t, overlap.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(2.5, 2.5))))
+
+	for _, id := range overlap {
+		assert.True(t, a.IntersectsCell(s2.CellFromCellID(id)) && b.IntersectsCell(s2.CellFromCellID(id)))
+	}
+
+	far, _ := PointsToPolygon([][]float64{{10, 10}, {11, 10}, {11, 11}, {10, 11}, {10, 10}})
+	assert.Equal(t, s2.CellUnion{}, CoverOverlap(a, far, 10, 1))
+	assert.Equal(t, s2.CellUnion{}, CoverOverlap(nil, far, 10, 1))
+}
+
+func TestCellUnionTokens(t *testing.T) {
+	cu := s2.CellUnion{s2.CellIDFromToken("14"), s2.CellIDFromToken("1c")}
+	tokens, cells := CellUnionTokens(cu)
+	assert.Equal(t, []string{"14", "1c"}, tokens)
+	assert.Equal(t, 2, len(cells))
+	assert.Equal(t, 4, len(cells[0]))
+
+	tokens, cells = CellUnionTokens(nil)
+	assert.Empty(t, tokens)
+	assert.Empty(t, cells)
+}
+
+func TestCoverPolygonIDs(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+
+	ids := CoverPolygonIDs(p, 4, 1)
+	var tokens []string
+	for _, id := range ids {
+		tokens = append(tokens, id.ToToken())
+	}
+	assert.Equal(t, []string{"055", "0ff", "101", "1ab"}, tokens)
+
+	cu, cuTokens, _, _ := CoverPolygon(p, 4, 1)
+	assert.Equal(t, s2.CellUnion(ids), cu)
+	assert.Equal(t, tokens, cuTokens)
+}
+
+func TestBBoxToRect(t *testing.T) {
+	r, err := BBoxToRect(0, 0, 1, 2)
+	assert.NoError(t, err)
+	assert.True(t, r.ContainsLatLng(s2.LatLngFromDegrees(0.5, 1.5)))
+	assert.False(t, r.ContainsLatLng(s2.LatLngFromDegrees(0.5, 2.5)))
+
+	// crossing the antimeridian
+	r, err = BBoxToRect(0, 179, 1, -179)
+	assert.NoError(t, err)
+	assert.True(t, r.ContainsLatLng(s2.LatLngFromDegrees(0.5, 180)))
+	assert.False(t, r.ContainsLatLng(s2.LatLngFromDegrees(0.5, 0)))
+
+	_, err = BBoxToRect(1, 0, 0, 1)
+	assert.Error(t, err)
+	_, err = BBoxToRect(0, 0, 91, 1)
+	assert.Error(t, err)
+}
+
+func TestCoverPolygonUniform(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+
+	cu, err := CoverPolygonUniform(p, 8)
+	assert.NoError(t, err)
+	assert.True(t, len(cu) > 4)
+	for _, id := range cu {
+		assert.Equal(t, 8, id.Level())
+	}
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+	// the estimate leaves out the cells only partly in the polygon
+	assert.True(t, UniformCellCount(p, 8) > 1 && UniformCellCount(p, 8) < float64(len(cu)))
+
+	// about 3e8 level 20 cells in the square
+	_, err = CoverPolygonUniform(p, 20)
+	assert.Equal(t, ErrTooManyCells, err)
+}
+
+func TestCoverRect(t *testing.T) {
+	r, _ := BBoxToRect(0, 0, 1, 1)
+	cu, tokens, cells := CoverRect(r, 8, 1)
+	assert.True(t, cu.IsValid())
+	assert.Equal(t, len(cu), len(tokens))
+	assert.Equal(t, len(cu), len(cells))
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+}
+
+func TestCoverComplement(t *testing.T) {
+	r, _ := BBoxToRect(0, 0, 3, 3)
+	p, _ := PointsToPolygon([][]float64{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}})
+
+	cu := CoverComplement(r, []*s2.Polygon{p}, 10, 1)
+	assert.True(t, cu.IsValid())
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(1.5, 1.5))))
+
+	_, full, _ := CoverRect(r, 10, 1)
+	assert.Equal(t, full, CellUnionToTokens(CoverComplement(r, nil, 10, 1)))
+}
+
+func TestCoverAnnulus(t *testing.T) {
+	center := Point{Lat: 40, Lng: 10}
+	cu := CoverAnnulus(center, 1000, 5000, 16, 1)
+	assert.True(t, cu.IsValid())
+	at := func(north float64) s2.CellID {
+		return s2.CellIDFromLatLng(s2.LatLngFromDegrees(center.Lat+north/1000/EarthRadius*180/math.Pi, center.Lng))
+	}
+	assert.False(t, cu.ContainsCellID(at(0)))
+	assert.False(t, cu.ContainsCellID(at(500)))
+	assert.True(t, cu.ContainsCellID(at(1000)))
+	assert.True(t, cu.ContainsCellID(at(3000)))
+	assert.False(t, cu.ContainsCellID(at(6000)))
+
+	// without an inner radius it is the covering of the disc
+	disc := CoverAnnulus(center, 0, 5000, 16, 1)
+	assert.True(t, disc.ContainsCellID(at(0)))
+	assert.True(t, disc.Contains(cu))
+}
+
+func TestClipCovering(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}})
+	cu := s2.CellUnion(CoverPolygonIDs(p, 10, 1))
+	r, _ := BBoxToRect(1, 1, 2, 2)
+
+	clipped := ClipCovering(cu, r, 10, 1)
+	assert.True(t, clipped.IsValid())
+	assert.True(t, clipped.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(1.5, 1.5))))
+	assert.False(t, clipped.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(3.5, 3.5))))
+	assert.True(t, clipped.ApproxArea() < cu.ApproxArea()/4)
+
+	outside, _ := BBoxToRect(10, 10, 11, 11)
+	assert.Empty(t, ClipCovering(cu, outside, 10, 1))
+}
+
+func TestCoverBorderBand(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+
+	// a 10km band inside the about 111km wide square
+	cu, err := CoverBorderBand(p, 10000, 12, 1)
+	assert.NoError(t, err)
+	assert.True(t, cu.IsValid())
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.02))))
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.98, 0.5))))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.2))))
+	assert.True(t, cu.ApproxArea() < p.Area()/2)
+
+	// a narrow band resolved at a fine level takes too many cells
+	_, err = CoverBorderBand(p, 1, 20, 1)
+	assert.Equal(t, ErrTooManyCells, err)
+}
+
+func TestPolygonArea(t *testing.T) {
+	p, err := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	assert.NoError(t, err)
+	// a 1 degree square at the equator is about 111km by 111km
+	assert.InDelta(t, 1.2364e10, PolygonArea(p), 1e8)
+
+	cu, _ := CoverPolygonUniform(p, 10)
+	assert.True(t, CoverageArea(cu) >= PolygonArea(p))
+	assert.InDelta(t, CoverageArea(cu), CoverageArea(append(cu, cu[0])), 1)
+	assert.Equal(t, 0.0, CoverageArea(nil))
+}
+
+func TestElevationCoordinates(t *testing.T) {
+	ring := [][]float64{{0, 0, 10}, {1, 0, 20}, {1, 1, 30}, {0, 1, 40}, {0, 0, 10}}
+	flat := [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+
+	p, err := PointsToPolygon(ring)
+	assert.NoError(t, err)
+	q, err := PointsToPolygon(flat)
+	assert.NoError(t, err)
+	assert.True(t, PolygonContainsPolygon(p, q) && PolygonContainsPolygon(q, p))
+	assert.Equal(t, CoverPolygonIDs(q, 10, 1), CoverPolygonIDs(p, 10, 1))
+
+	repaired, err := RepairPolygon(ring)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(repaired))
+	assert.Equal(t, 5, len(SimplifyPolygon(ring, 1)))
+
+	dense := DensifyPolygon(ring[:2], 50000)
+	assert.Equal(t, 4, len(dense))
+	assert.InDelta(t, 13.33, dense[1][2], 0.01)
+	assert.Equal(t, 2, len(DensifyPolygon([][]float64{{0, 0}, {1, 0, 20}}, 50000)[1]))
+
+	fs, err := DecodeGeoJSON([]byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0,10],[1,0,20],[1,1,30],[0,0,10]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[2,2,-5]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[3,3]}}]}`))
+	assert.NoError(t, err)
+	min, max, ok := ElevationRange(fs)
+	assert.True(t, ok)
+	assert.Equal(t, -5.0, min)
+	assert.Equal(t, 30.0, max)
+	_, _, ok = ElevationRange(fs[2:])
+	assert.False(t, ok)
+}
+
+func TestCellUnionBounds(t *testing.T) {
+	cu := s2.CellUnion{s2.CellIDFromToken("14d607"), s2.CellIDFromLatLng(s2.LatLngFromDegrees(89.9, 10)).Parent(4)}
+	bounds := CellUnionBounds(cu)
+	assert.Equal(t, 2, len(bounds))
+	for i, id := range cu {
+		b, cell := bounds[i], s2.CellFromCellID(id)
+		assert.True(t, b.MinLat < b.MaxLat && b.MinLng < b.MaxLng)
+		for _, v := range EdgesOfCell(cell) {
+			assert.True(t, v[0] >= b.MinLat && v[0] <= b.MaxLat)
+			assert.True(t, v[1] >= b.MinLng && v[1] <= b.MaxLng)
+		}
+	}
+	// the cell at the pole touches it, unlike any of its vertices
+	assert.Equal(t, 90.0, bounds[1].MaxLat)
+}
+
+func TestLngLatCells(t *testing.T) {
+	cells := [][][]float64{{{38, -34}, {1, 2}}}
+	assert.Equal(t, [][][]float64{{{-34, 38}, {2, 1}}}, LngLatCells(cells))
+	assert.Equal(t, 38.0, cells[0][0][0])
+}
+
+func TestReprojectWGS84ToMercator(t *testing.T) {
+	cells := ReprojectWGS84ToMercator([][][]float64{{{0, 0}, {180, 0}, {-90, 45}, {0, 90}}})
+	assert.InDelta(t, 0, cells[0][0][0], 1e-6)
+	assert.InDelta(t, 0, cells[0][0][1], 1e-6)
+	assert.InDelta(t, 20037508.34, cells[0][1][0], 0.01)
+	assert.InDelta(t, -10018754.17, cells[0][2][0], 0.01)
+	assert.InDelta(t, 5621521.49, cells[0][2][1], 0.01)
+	assert.InDelta(t, 20037508.34, cells[0][3][1], 0.01)
+}
+
+func TestRoundCells(t *testing.T) {
+	cells := [][][]float64{{{38.123456789, -34.987654321}, {1, 2}}}
+	assert.Equal(t, [][][]float64{{{38.1234568, -34.9876543}, {1, 2}}}, RoundCells(cells, 7))
+	assert.Equal(t, [][][]float64{{{38, -35}, {1, 2}}}, RoundCells(cells, 0))
+	assert.Equal(t, cells, RoundCells(cells, -1))
+	assert.Equal(t, 38.123456789, cells[0][0][0])
+}
+
+func TestCellIDToPath(t *testing.T) {
+	id := s2.CellIDFromFace(2).Children()[0].Children()[1].Children()[3]
+	assert.Equal(t, "2/013", CellIDToPath(id))
+	assert.Equal(t, "4/", CellIDToPath(s2.CellIDFromFace(4)))
+	assert.Equal(t, id.String(), CellIDToPath(id))
+	assert.Equal(t, "", CellIDToPath(s2.CellID(0)))
+}
+
+func TestCellUnionToTokens(t *testing.T) {
+	cu := s2.CellUnion{s2.CellIDFromToken("14"), s2.CellIDFromToken("1c")}
+	assert.Equal(t, []string{"14", "1c"}, CellUnionToTokens(cu))
+	assert.Equal(t, []string{}, CellUnionToTokens(nil))
+}
+
+func TestPointCellID(t *testing.T) {
+	cell, token, _ := CoverPoint(Point{Lat: 38.34, Lng: 34.34}, 10)
+	id := PointCellID(Point{Lat: 38.34, Lng: 34.34}, 10)
+	assert.Equal(t, cell.ID(), id)
+	assert.Equal(t, token, id.ToToken())
+	assert.Equal(t, 10, id.Level())
+}
+
+func TestFeatureRect(t *testing.T) {
+	f := geojson.NewPolygonFeature([][][]float64{{{0, 0}, {2, 0}, {2, 1}, {0, 1}, {0, 0}}})
+	r := FeatureRect(f)
+	assert.True(t, r.ContainsLatLng(s2.LatLngFromDegrees(0.5, 1.5)))
+	assert.False(t, r.ContainsLatLng(s2.LatLngFromDegrees(1.5, 1.5)))
+
+	// the bbox of the feature takes precedence over the geometry
+	f.BoundingBox = []float64{10, 10, 11, 11}
+	assert.True(t, FeatureRect(f).ContainsLatLng(s2.LatLngFromDegrees(10.5, 10.5)))
+	f.BoundingBox = nil
+	f.Geometry.BoundingBox = []float64{10, 10, 0, 11, 11, 0}
+	assert.True(t, FeatureRect(f).ContainsLatLng(s2.LatLngFromDegrees(10.5, 10.5)))
+
+	p := geojson.NewPointFeature([]float64{3, 4})
+	assert.True(t, FeatureRect(p).ContainsLatLng(s2.LatLngFromDegrees(4, 3)))
+	assert.True(t, FeatureRect(geojson.NewFeature(nil)).IsEmpty())
+
+	l := geojson.NewMultiLineStringFeature([][]float64{{0, 0}, {1, 1}}, [][]float64{{5, 5}, {6, 5}})
+	assert.True(t, FeatureRect(l).ContainsLatLng(s2.LatLngFromDegrees(5, 5.5)))
+	assert.False(t, FeatureRect(l).ContainsLatLng(s2.LatLngFromDegrees(5.5, 7)))
+
+	// invalid positions are skipped
+	assert.True(t, FeatureRect(geojson.NewPointFeature([]float64{})).IsEmpty())
+	m := geojson.NewMultiPointFeature([]float64{1}, []float64{3, 4})
+	assert.True(t, FeatureRect(m).ContainsLatLng(s2.LatLngFromDegrees(4, 3)))
+}
@@ -0,0 +1,280 @@
+package geo
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
+	"math"
+	"sort"
+)
+
+// geobufPrecision is the number of decimals of the encoded coordinates
+const geobufPrecision = 1e6
+
+// geobuf geometry types
+var geobufTypes = map[geojson.GeometryType]uint64{
+	geojson.GeometryPoint:           0,
+	geojson.GeometryMultiPoint:      1,
+	geojson.GeometryLineString:      2,
+	geojson.GeometryMultiLineString: 3,
+	geojson.GeometryPolygon:         4,
+	geojson.GeometryMultiPolygon:    5,
+	geojson.GeometryCollection:      6,
+}
+
+// pbWriter appends protocol buffer fields to a buffer
+type pbWriter struct {
+	buf []byte
+}
+
+func (w *pbWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf = append(w.buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func (w *pbWriter) key(field, wireType int) {
+	w.varint(uint64(field<<3 | wireType))
+}
+
+func (w *pbWriter) uintField(field int, v uint64) {
+	w.key(field, 0)
+	w.varint(v)
+}
+
+func (w *pbWriter) sintField(field int, v int64) {
+	w.uintField(field, uint64(v<<1^v>>63))
+}
+
+func (w *pbWriter) doubleField(field int, v float64) {
+	w.key(field, 1)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *pbWriter) bytesField(field int, b []byte) {
+	w.key(field, 2)
+	w.varint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *pbWriter) packedUints(field int, vs []uint64) {
+	if len(vs) == 0 {
+		return
+	}
+	var p pbWriter
+	for _, v := range vs {
+		p.varint(v)
+	}
+	w.bytesField(field, p.buf)
+}
+
+func (w *pbWriter) packedSints(field int, vs []int64) {
+	if len(vs) == 0 {
+		return
+	}
+	var p pbWriter
+	for _, v := range vs {
+		p.varint(uint64(v<<1 ^ v>>63))
+	}
+	w.bytesField(field, p.buf)
+}
+
+// EncodeGeobuf encodes the feature collection to geobuf, the protocol buffer encoding of geojson,
+// with 2 dimensions and 6 decimals precision
+func EncodeGeobuf(fc *geojson.FeatureCollection) ([]byte, error) {
+	keys := make(map[string]uint64)
+	var data pbWriter
+	for _, f := range fc.Features {
+		for _, k := range sortedKeys(f.Properties) {
+			if _, ok := keys[k]; !ok {
+				keys[k] = uint64(len(keys))
+				data.bytesField(1, []byte(k))
+			}
+		}
+	}
+
+	var collection pbWriter
+	for _, f := range fc.Features {
+		feature, err := encodeGeobufFeature(f, keys)
+		if err != nil {
+			return nil, err
+		}
+		collection.bytesField(1, feature)
+	}
+	data.bytesField(4, collection.buf)
+	return data.buf, nil
+}
+
+func encodeGeobufFeature(f *geojson.Feature, keys map[string]uint64) ([]byte, error) {
+	var w pbWriter
+	if f.Geometry == nil {
+		return nil, fmt.Errorf("geobuf: feature without geometry")
+	}
+	g, err := encodeGeobufGeometry(f.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	w.bytesField(1, g)
+
+	switch id := f.ID.(type) {
+	case nil:
+	case string:
+		w.bytesField(11, []byte(id))
+	case float64:
+		if id == math.Trunc(id) {
+			w.sintField(12, int64(id))
+		} else {
+			w.bytesField(11, []byte(fmt.Sprint(id)))
+		}
+	default:
+		w.bytesField(11, []byte(fmt.Sprint(id)))
+	}
+
+	var props []uint64
+	for i, k := range sortedKeys(f.Properties) {
+		v, err := encodeGeobufValue(f.Properties[k])
+		if err != nil {
+			return nil, err
+		}
+		w.bytesField(13, v)
+		props = append(props, keys[k], uint64(i))
+	}
+	w.packedUints(14, props)
+	return w.buf, nil
+}
+
+func encodeGeobufGeometry(g *geojson.Geometry) ([]byte, error) {
+	t, ok := geobufTypes[g.Type]
+	if !ok {
+		return nil, fmt.Errorf("geobuf: unsupported geometry type %q", g.Type)
+	}
+	var w pbWriter
+	w.uintField(1, t)
+
+	var lengths []uint64
+	var coords []int64
+	switch g.Type {
+	case geojson.GeometryPoint:
+		coords = appendGeobufLine(coords, [][]float64{g.Point}, false)
+	case geojson.GeometryMultiPoint:
+		coords = appendGeobufLine(coords, g.MultiPoint, false)
+	case geojson.GeometryLineString:
+		coords = appendGeobufLine(coords, g.LineString, false)
+	case geojson.GeometryMultiLineString:
+		lengths, coords = appendGeobufLines(lengths, coords, g.MultiLineString, false)
+	case geojson.GeometryPolygon:
+		lengths, coords = appendGeobufLines(lengths, coords, g.Polygon, true)
+	case geojson.GeometryMultiPolygon:
+		// a single polygon with a single ring needs no lengths
+		if len(g.MultiPolygon) != 1 || len(g.MultiPolygon[0]) != 1 {
+			lengths = append(lengths, uint64(len(g.MultiPolygon)))
+			for _, rings := range g.MultiPolygon {
+				lengths = append(lengths, uint64(len(rings)))
+				for _, ring := range rings {
+					lengths = append(lengths, uint64(len(ring)-1))
+				}
+			}
+		}
+		for _, rings := range g.MultiPolygon {
+			for _, ring := range rings {
+				coords = appendGeobufLine(coords, ring, true)
+			}
+		}
+	case geojson.GeometryCollection:
+		for _, child := range g.Geometries {
+			c, err := encodeGeobufGeometry(child)
+			if err != nil {
+				return nil, err
+			}
+			w.bytesField(4, c)
+		}
+	}
+	w.packedUints(2, lengths)
+	w.packedSints(3, coords)
+	return w.buf, nil
+}
+
+// appendGeobufLines appends the lines, with their lengths unless there is a single line
+func appendGeobufLines(lengths []uint64, coords []int64, lines [][][]float64, closed bool) ([]uint64, []int64) {
+	for _, line := range lines {
+		if len(lines) != 1 {
+			n := len(line)
+			if closed {
+				n--
+			}
+			lengths = append(lengths, uint64(n))
+		}
+		coords = appendGeobufLine(coords, line, closed)
+	}
+	return lengths, coords
+}
+
+// appendGeobufLine appends the delta encoded positions of the line, omitting the closing position of rings
+func appendGeobufLine(coords []int64, line [][]float64, closed bool) []int64 {
+	n := len(line)
+	if closed && n > 0 {
+		n--
+	}
+	var sum [2]int64
+	for _, pos := range line[:n] {
+		for j := 0; j < 2 && j < len(pos); j++ {
+			v := int64(math.Round(pos[j]*geobufPrecision)) - sum[j]
+			coords = append(coords, v)
+			sum[j] += v
+		}
+	}
+	return coords
+}
+
+func encodeGeobufValue(v interface{}) ([]byte, error) {
+	var w pbWriter
+	switch v := v.(type) {
+	case string:
+		w.bytesField(1, []byte(v))
+	case bool:
+		b := uint64(0)
+		if v {
+			b = 1
+		}
+		w.uintField(5, b)
+	case float64:
+		switch {
+		case v != math.Trunc(v) || math.IsInf(v, 0):
+			w.doubleField(2, v)
+		case v >= 0:
+			w.uintField(3, uint64(v))
+		default:
+			w.uintField(4, uint64(-v))
+		}
+	case int:
+		if v >= 0 {
+			w.uintField(3, uint64(v))
+		} else {
+			w.uintField(4, uint64(-v))
+		}
+	default:
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		w.bytesField(6, j)
+	}
+	return w.buf, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CellUnionToGeobuf encodes the cells of the union to a geobuf feature collection
+func CellUnionToGeobuf(cu s2.CellUnion) ([]byte, error) {
+	return EncodeGeobuf(CellUnionToFeatureCollection(cu))
+}
@@ -0,0 +1,33 @@
+package geo
+
+import (
+	"encoding/hex"
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeGeobuf(t *testing.T) {
+	fc, err := geojson.UnmarshalFeatureCollection([]byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":7,"properties":{"name":"p","v":-3},"geometry":{"type":"Point","coordinates":[1.5,-2]}},
+		{"type":"Feature","properties":{"ok":true},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}]}`))
+	assert.NoError(t, err)
+
+	b, err := EncodeGeobuf(fc)
+	assert.NoError(t, err)
+	assert.Equal(t, "0a046e616d650a01760a026f6b223b0a1f0a0c08001a08c08db701ff91f401600e6a030a01706a0220037204000001010a180a0e08041a0a000080897a000080897a6a02280172020200", hex.EncodeToString(b))
+
+	_, err = EncodeGeobuf(&geojson.FeatureCollection{Features: []*geojson.Feature{{}}})
+	assert.Error(t, err)
+}
+
+func TestCellUnionToGeobuf(t *testing.T) {
+	cu := s2.CellUnion{s2.CellIDFromToken("14"), s2.CellIDFromToken("1c")}
+	b, err := CellUnionToGeobuf(cu)
+	assert.NoError(t, err)
+
+	j, err := CellUnionToFeatureCollection(cu).MarshalJSON()
+	assert.NoError(t, err)
+	assert.True(t, len(b) < len(j))
+}
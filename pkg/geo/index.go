@@ -0,0 +1,191 @@
+package geo
+
+import (
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
+	"math"
+	"sort"
+)
+
+// FeatureIndex indexes the polygons of features in a shape index for repeated point and circle queries
+type FeatureIndex struct {
+	Features []*geojson.Feature
+	index    *s2.ShapeIndex
+	// owners maps the indexed polygons to the positions of their features
+	owners map[s2.Shape]int
+	// bounds maps the indexed polygons to their bounding caps
+	bounds map[s2.Shape]s2.Cap
+}
+
+// NewShapeIndexFromFeatures adds each polygon of the features with its holes to a shape index, skipping the
+// polygons with empty or degenerate rings, and returns it with the position of the feature of each shape by shape id
+func NewShapeIndexFromFeatures(fs []*geojson.Feature) (*s2.ShapeIndex, []int) {
+	index := s2.NewShapeIndex()
+	var owners []int
+	for i, f := range fs {
+		for _, rings := range polygonParts(f.Geometry) {
+			p, err := ringsToPolygon(rings)
+			if err != nil {
+				continue
+			}
+			index.Add(p)
+			owners = append(owners, i)
+		}
+	}
+	return index, owners
+}
+
+// NewFeatureIndex indexes the polygons of the features with their holes, skipping the polygons with degenerate rings
+func NewFeatureIndex(fs []*geojson.Feature) *FeatureIndex {
+	index, positions := NewShapeIndexFromFeatures(fs)
+	fi := &FeatureIndex{Features: fs, index: index, owners: make(map[s2.Shape]int), bounds: make(map[s2.Shape]s2.Cap)}
+	for id, i := range positions {
+		shape := index.Shape(int32(id))
+		fi.owners[shape] = i
+		fi.bounds[shape] = shape.(*s2.Polygon).CapBound()
+	}
+	return fi
+}
+
+// ContainsPoint returns the positions of the features containing the point in ascending order
+func (fi *FeatureIndex) ContainsPoint(p Point) []int {
+	q := s2.NewContainsPointQuery(fi.index, s2.VertexModelSemiOpen)
+	return fi.positions(q.ContainingShapes(s2.PointFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lng))))
+}
+
+// IntersectsCircle returns the positions of the features intersecting the circle in ascending order,
+// that is containing its center or having an edge closer than the radius to the center. The containment is
+// queried through the index, but as the vendored s2 has no closest edge query the edges are found by a linear
+// scan over the polygons, skipping those whose bounding cap is farther than the radius.
+func (fi *FeatureIndex) IntersectsCircle(p Point, radiusMeters float64) []int {
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lng))
+	radius := s1.Angle(radiusMeters / 1000 / EarthRadius)
+
+	shapes := s2.NewContainsPointQuery(fi.index, s2.VertexModelSemiOpen).ContainingShapes(center)
+	for shape := range fi.owners {
+		if capDistance(fi.bounds[shape], center) > radius {
+			continue
+		}
+		for e := 0; e < shape.NumEdges(); e++ {
+			edge := shape.Edge(e)
+			if s2.DistanceFromSegment(center, edge.V0, edge.V1) <= radius {
+				shapes = append(shapes, shape)
+				break
+			}
+		}
+	}
+	return fi.positions(shapes)
+}
+
+// Neighbor is the position of a feature and the distance in meters of its boundary to a query point
+type Neighbor struct {
+	Feature        int
+	DistanceMeters float64
+}
+
+// NearestFeature returns the feature whose polygon boundary is closest to the point,
+// false when no polygons are indexed
+func (fi *FeatureIndex) NearestFeature(p Point) (Neighbor, bool) {
+	nearest := fi.KNearestFeatures(p, 1)
+	if len(nearest) == 0 {
+		return Neighbor{}, false
+	}
+	return nearest[0], true
+}
+
+// KNearestFeatures returns up to k features sorted by the distance of their polygon boundaries to the point,
+// ties in the order of the features, all of them for a negative k. As the vendored s2 has no closest edge query
+// it is a linear scan over the edges of the polygons, in the order of the distances of their bounding caps so
+// that it stops at the first polygon whose cap is farther than the k nearest features found.
+func (fi *FeatureIndex) KNearestFeatures(p Point, k int) []Neighbor {
+	pt := s2.PointFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lng))
+	shapes := make([]s2.Shape, 0, len(fi.owners))
+	lower := make(map[s2.Shape]float64, len(fi.owners))
+	for shape := range fi.owners {
+		shapes = append(shapes, shape)
+		lower[shape] = capDistance(fi.bounds[shape], pt).Radians() * EarthRadius * 1000
+	}
+	sort.Slice(shapes, func(a, b int) bool { return lower[shapes[a]] < lower[shapes[b]] })
+
+	distances := make(map[int]float64)
+	kth, stale := math.Inf(1), false
+	for _, shape := range shapes {
+		if k >= 0 && len(distances) >= k {
+			if stale {
+				kth, stale = kthDistance(distances, k), false
+			}
+			// ties with the kth distance are kept for the order of the features
+			if lower[shape] > kth {
+				break
+			}
+		}
+		i := fi.owners[shape]
+		d := shapeDistanceMeters(shape, pt)
+		if min, ok := distances[i]; !ok || d < min {
+			distances[i], stale = d, true
+		}
+	}
+
+	neighbors := make([]Neighbor, 0, len(distances))
+	for i, d := range distances {
+		neighbors = append(neighbors, Neighbor{Feature: i, DistanceMeters: d})
+	}
+	sort.Slice(neighbors, func(a, b int) bool {
+		if neighbors[a].DistanceMeters != neighbors[b].DistanceMeters {
+			return neighbors[a].DistanceMeters < neighbors[b].DistanceMeters
+		}
+		return neighbors[a].Feature < neighbors[b].Feature
+	})
+	if k >= 0 && k < len(neighbors) {
+		neighbors = neighbors[:k]
+	}
+	return neighbors
+}
+
+// kthDistance returns the kth smallest of the distances, 1 being the smallest, and -inf for a k of 0
+func kthDistance(distances map[int]float64, k int) float64 {
+	if k == 0 {
+		return math.Inf(-1)
+	}
+	ds := make([]float64, 0, len(distances))
+	for _, d := range distances {
+		ds = append(ds, d)
+	}
+	sort.Float64s(ds)
+	return ds[k-1]
+}
+
+// capDistance returns a lower bound of the distance of the point to anything in the cap, 0 inside of it
+func capDistance(c s2.Cap, pt s2.Point) s1.Angle {
+	if d := c.Center().Distance(pt) - c.Radius(); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// shapeDistanceMeters returns the distance in meters of the point to the closest edge of the shape
+func shapeDistanceMeters(shape s2.Shape, pt s2.Point) float64 {
+	min := math.Inf(1)
+	for e := 0; e < shape.NumEdges(); e++ {
+		edge := shape.Edge(e)
+		if d := distanceMeters(pt, edge.V0, edge.V1); d < min {
+			min = d
+		}
+	}
+	return min
+}
+
+// positions returns the distinct positions of the features of the shapes in ascending order
+func (fi *FeatureIndex) positions(shapes []s2.Shape) []int {
+	seen := make(map[int]bool)
+	positions := []int{}
+	for _, s := range shapes {
+		if i, ok := fi.owners[s]; ok && !seen[i] {
+			seen[i] = true
+			positions = append(positions, i)
+		}
+	}
+	sort.Ints(positions)
+	return positions
+}
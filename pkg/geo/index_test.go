@@ -0,0 +1,119 @@
+package geo
+
+import (
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func indexFeatures() []*geojson.Feature {
+	return []*geojson.Feature{
+		geojson.NewPolygonFeature([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}),
+		geojson.NewPointFeature([]float64{5, 5}),
+		geojson.NewPolygonFeature([][][]float64{{{0.5, 0.5}, {2, 0.5}, {2, 2}, {0.5, 2}, {0.5, 0.5}}}),
+		geojson.NewPolygonFeature([][][]float64{{{10, 10}, {11, 10}, {11, 11}, {10, 11}, {10, 10}}}),
+	}
+}
+
+func TestNewShapeIndexFromFeatures(t *testing.T) {
+	index, owners := NewShapeIndexFromFeatures(indexFeatures())
+	assert.Equal(t, 3, index.Len())
+	assert.Equal(t, []int{0, 2, 3}, owners)
+}
+
+func TestFeatureIndexContainsPoint(t *testing.T) {
+	fi := NewFeatureIndex(indexFeatures())
+	assert.Equal(t, []int{0}, fi.ContainsPoint(Point{Lat: 0.2, Lng: 0.2}))
+	assert.Equal(t, []int{0, 2}, fi.ContainsPoint(Point{Lat: 0.7, Lng: 0.7}))
+	assert.Equal(t, []int{}, fi.ContainsPoint(Point{Lat: 5, Lng: 5}))
+}
+
+func TestFeatureIndexHoles(t *testing.T) {
+	hole := [][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}},
+	}
+	fi := NewFeatureIndex([]*geojson.Feature{
+		geojson.NewPolygonFeature(hole),
+		geojson.NewMultiPolygonFeature([][][]float64{{{20, 20}, {21, 20}, {21, 21}, {20, 21}, {20, 20}}}, hole),
+	})
+	assert.Equal(t, 3, fi.index.Len())
+	assert.Equal(t, []int{0, 1}, fi.ContainsPoint(Point{Lat: 2, Lng: 2}))
+	assert.Equal(t, []int{1}, fi.ContainsPoint(Point{Lat: 20.5, Lng: 20.5}))
+	assert.Equal(t, []int{}, fi.ContainsPoint(Point{Lat: 5, Lng: 5}))
+	assert.Equal(t, []int{}, fi.ContainsPoint(Point{Lat: 50, Lng: 100}))
+}
+
+func TestFeatureIndexIntersectsCircle(t *testing.T) {
+	fi := NewFeatureIndex(indexFeatures())
+	// about 11km east of the first square
+	p := Point{Lat: 0.2, Lng: 1.1}
+	assert.Equal(t, []int{}, fi.IntersectsCircle(p, 10000))
+	assert.Equal(t, []int{0}, fi.IntersectsCircle(p, 12000))
+	assert.Equal(t, []int{0, 2}, fi.IntersectsCircle(Point{Lat: 0.7, Lng: 0.7}, 1))
+
+	// the circles reaching the far square past its bounding cap
+	far := Point{Lat: 10.5, Lng: 12}
+	assert.Equal(t, []int{}, fi.IntersectsCircle(far, 100000))
+	assert.Equal(t, []int{3}, fi.IntersectsCircle(far, 115000))
+	assert.Equal(t, []int{}, fi.IntersectsCircle(Point{Lat: -30, Lng: -30}, 1000000))
+}
+
+func TestCapDistance(t *testing.T) {
+	c := s2.CapFromCenterAngle(s2.PointFromLatLng(s2.LatLngFromDegrees(0, 0)), s1.Degree)
+	assert.Equal(t, s1.Angle(0), capDistance(c, s2.PointFromLatLng(s2.LatLngFromDegrees(0.5, 0))))
+	assert.InDelta(t, s1.Degree.Radians(), capDistance(c, s2.PointFromLatLng(s2.LatLngFromDegrees(2, 0))).Radians(), 1e-9)
+}
+
+func TestFeatureIndexNearestFeature(t *testing.T) {
+	fi := NewFeatureIndex(indexFeatures())
+	n, ok := fi.NearestFeature(Point{Lat: 9, Lng: 10.5})
+	assert.True(t, ok)
+	assert.Equal(t, 3, n.Feature)
+	assert.InDelta(t, 111195, n.DistanceMeters, 100)
+
+	// inside a polygon the distance is to its boundary
+	n, ok = fi.NearestFeature(Point{Lat: 0.1, Lng: 0.5})
+	assert.True(t, ok)
+	assert.Equal(t, 0, n.Feature)
+	assert.InDelta(t, 11119, n.DistanceMeters, 10)
+
+	_, ok = NewFeatureIndex(nil).NearestFeature(Point{})
+	assert.False(t, ok)
+}
+
+func TestFeatureIndexKNearestFeatures(t *testing.T) {
+	fi := NewFeatureIndex(indexFeatures())
+	nearest := fi.KNearestFeatures(Point{Lat: 3, Lng: 3}, 2)
+	assert.Len(t, nearest, 2)
+	assert.Equal(t, 2, nearest[0].Feature)
+	assert.Equal(t, 0, nearest[1].Feature)
+	assert.True(t, nearest[0].DistanceMeters < nearest[1].DistanceMeters)
+
+	assert.Len(t, fi.KNearestFeatures(Point{Lat: 3, Lng: 3}, 10), 3)
+	assert.Len(t, fi.KNearestFeatures(Point{Lat: 3, Lng: 3}, -1), 3)
+	assert.Empty(t, fi.KNearestFeatures(Point{Lat: 3, Lng: 3}, 0))
+}
+
+func TestFeatureIndexKNearestFeaturesPruned(t *testing.T) {
+	// a row of squares eastwards and a multipolygon whose far part comes first in the feature
+	var fs []*geojson.Feature
+	for i := 0; i < 20; i++ {
+		x := float64(2 * i)
+		fs = append(fs, geojson.NewPolygonFeature([][][]float64{{{x, 0}, {x + 1, 0}, {x + 1, 1}, {x, 1}, {x, 0}}}))
+	}
+	fs = append(fs, geojson.NewMultiPolygonFeature(
+		[][][]float64{{{50, 50}, {51, 50}, {51, 51}, {50, 51}, {50, 50}}},
+		[][][]float64{{{-2, 0}, {-1.5, 0}, {-1.5, 1}, {-2, 1}, {-2, 0}}},
+	))
+	fi := NewFeatureIndex(fs)
+
+	all := fi.KNearestFeatures(Point{Lat: 0.5, Lng: -0.5}, -1)
+	assert.Len(t, all, 21)
+	for k := 0; k <= 21; k++ {
+		assert.Equal(t, all[:k], fi.KNearestFeatures(Point{Lat: 0.5, Lng: -0.5}, k))
+	}
+	assert.Equal(t, []int{0, 20, 1}, []int{all[0].Feature, all[1].Feature, all[2].Feature})
+}
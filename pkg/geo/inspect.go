@@ -0,0 +1,149 @@
+package geo
+
+import (
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
+	"math"
+)
+
+const (
+	// MaxWidthVertices is the max number of vertices of the rings whose min width is measured, as MinWidth is
+	// quadratic in the number of vertices
+	MaxWidthVertices = 1000
+	// MaxWidthTotalVertices is the max total number of vertices of the rings InspectFeatures measures the min
+	// width of
+	MaxWidthTotalVertices = 5000
+)
+
+// RingReport describes the structure of a polygon ring as it is converted to a s2 loop
+type RingReport struct {
+	Feature  int         `json:"feature"`
+	ID       interface{} `json:"id,omitempty"`
+	Ring     int         `json:"ring"`
+	Vertices int         `json:"vertices"`
+	Closed   bool        `json:"closed"`
+	Winding  string      `json:"winding"`
+	Valid    bool        `json:"valid"`
+	Error    string      `json:"error,omitempty"`
+	// LongestEdge and MinWidth are measured in meters for valid rings
+	LongestEdge float64 `json:"longest_edge_meters,omitempty"`
+	MinWidth    float64 `json:"min_width_meters,omitempty"`
+	// WidthSkipped is set when the min width of a valid ring is not measured as it has too many vertices
+	WidthSkipped bool `json:"min_width_skipped,omitempty"`
+}
+
+// InspectRing reports the number of vertices without the closing one, whether the ring is closed, its winding
+// and whether it converts to a valid loop. The winding is ccw or cw by the sign of the signed area of the ring,
+// empty when the area is zero. Clockwise rings are covered as the complement of the ring by the s2 loops.
+// The min width is skipped for rings of more than MaxWidthVertices vertices.
+func InspectRing(points [][]float64) RingReport {
+	return inspectRing(points, MaxWidthVertices)
+}
+
+// inspectRing reports the structure of the ring like InspectRing, measuring the min width of rings of at most
+// maxWidthVertices vertices
+func inspectRing(points [][]float64, maxWidthVertices int) RingReport {
+	var r RingReport
+	for _, pt := range points {
+		if len(pt) < 2 {
+			r.Error = "invalid coordinate in ring"
+			return r
+		}
+	}
+	if len(points) == 0 {
+		r.Error = ErrEmptyRing.Error()
+		return r
+	}
+	r.Closed = len(points) > 1 && samePosition(points[0], points[len(points)-1])
+	ring := points
+	if r.Closed {
+		ring = points[:len(points)-1]
+	}
+	r.Vertices = len(ring)
+
+	pts := ringPoints(ring)
+	area := 0.0
+	for i := 1; i+1 < len(pts); i++ {
+		area += s2.SignedArea(pts[0], pts[i], pts[i+1])
+	}
+	switch {
+	case area > 0:
+		r.Winding = "ccw"
+	case area < 0:
+		r.Winding = "cw"
+	}
+
+	loop := s2.LoopFromPoints(pts)
+	if err := loop.Validate(); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Valid = true
+	p := s2.PolygonFromLoops([]*s2.Loop{loop})
+	r.LongestEdge = LongestEdge(p)
+	if r.Vertices <= maxWidthVertices {
+		r.MinWidth = MinWidth(p)
+	} else {
+		r.WidthSkipped = true
+	}
+	return r
+}
+
+// LongestEdge returns the length in meters of the longest edge of the loops of the polygon, long edges
+// bulge away from their straight lng/lat line and may need densifying
+func LongestEdge(p *s2.Polygon) float64 {
+	longest := 0.0
+	for _, l := range p.Loops() {
+		for i := 0; i < l.NumEdges(); i++ {
+			e := l.Edge(i)
+			longest = math.Max(longest, e.V0.Distance(e.V1).Radians())
+		}
+	}
+	return longest * EarthRadius * 1000
+}
+
+// MinWidth approximates the width in meters of the thinnest part of the polygon as the min distance of a
+// vertex to the edges of the loops not touching it, 0 for polygons without edges. It is quadratic in the
+// number of vertices.
+func MinWidth(p *s2.Polygon) float64 {
+	width := math.Inf(1)
+	for _, l := range p.Loops() {
+		for j := 0; j < l.NumEdges(); j++ {
+			v := l.Vertex(j)
+			for _, l2 := range p.Loops() {
+				for i := 0; i < l2.NumEdges(); i++ {
+					// the edges of the vertex, or of a duplicate of it, touch it
+					if e := l2.Edge(i); e.V0 != v && e.V1 != v {
+						width = math.Min(width, distanceMeters(v, e.V0, e.V1))
+					}
+				}
+			}
+		}
+	}
+	if math.IsInf(width, 1) {
+		return 0
+	}
+	return width
+}
+
+// InspectFeatures reports the structure of each polygon ring of the features, skipping the min width of the
+// rings past the first MaxWidthTotalVertices measured vertices
+func InspectFeatures(fs []*geojson.Feature) []RingReport {
+	reports := []RingReport{}
+	budget := MaxWidthTotalVertices
+	for i, f := range fs {
+		for j, ring := range PolygonRings(f.Geometry) {
+			max := MaxWidthVertices
+			if budget < max {
+				max = budget
+			}
+			r := inspectRing(ring, max)
+			if r.Valid && !r.WidthSkipped {
+				budget -= r.Vertices
+			}
+			r.Feature, r.ID, r.Ring = i, f.ID, j
+			reports = append(reports, r)
+		}
+	}
+	return reports
+}
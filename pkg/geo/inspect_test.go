@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"github.com/golang/geo/s2"
+	"github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInspectRing(t *testing.T) {
+	r := InspectRing([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	// a degree at the equator is about 111km
+	assert.InDelta(t, 111195, r.LongestEdge, 1)
+	assert.InDelta(t, 111178, r.MinWidth, 1)
+	r.LongestEdge, r.MinWidth = 0, 0
+	assert.Equal(t, RingReport{Vertices: 4, Closed: true, Winding: "ccw", Valid: true}, r)
+
+	r = InspectRing([][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}})
+	r.LongestEdge, r.MinWidth = 0, 0
+	assert.Equal(t, RingReport{Vertices: 4, Closed: false, Winding: "cw", Valid: true}, r)
+
+	r = InspectRing([][]float64{{0, 0}, {1, 0}, {1, 0}, {0, 1}, {0, 0}})
+	assert.False(t, r.Valid)
+	assert.Contains(t, r.Error, "duplicate vertex")
+
+	r = InspectRing([][]float64{{0, 0}, {1, 1}, {0, 0}})
+	assert.False(t, r.Valid)
+	assert.Equal(t, "", r.Winding)
+
+	assert.Equal(t, ErrEmptyRing.Error(), InspectRing(nil).Error)
+	assert.Equal(t, "invalid coordinate in ring", InspectRing([][]float64{{0}}).Error)
+}
+
+func TestLongestEdgeMinWidth(t *testing.T) {
+	// a 1 by 0.01 degree sliver
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 0.01}, {0, 0.01}, {0, 0}})
+	assert.InDelta(t, 111195, LongestEdge(p), 1)
+	assert.InDelta(t, 1112, MinWidth(p), 1)
+
+	// the altitude of the triangle
+	p, _ = PointsToPolygon([][]float64{{0, 0}, {0.1, 0}, {0.05, 0.02}, {0, 0}})
+	assert.InDelta(t, 2224, MinWidth(p), 1)
+
+	// the wall between the shell and the hole
+	f := geojson.NewPolygonGeometry([][][]float64{
+		{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+		{{0.1, 0.1}, {0.1, 0.9}, {0.99, 0.9}, {0.99, 0.1}, {0.1, 0.1}},
+	})
+	p, _ = GeometryToPolygon(f)
+	assert.InDelta(t, 1112, MinWidth(p), 1)
+
+	empty := s2.PolygonFromLoops([]*s2.Loop{s2.EmptyLoop()})
+	assert.Equal(t, 0.0, LongestEdge(empty))
+	assert.Equal(t, 0.0, MinWidth(empty))
+}
+
+func TestInspectFeatures(t *testing.T) {
+	fs := []*geojson.Feature{
+		geojson.NewPointFeature([]float64{0, 0}),
+		geojson.NewPolygonFeature([][][]float64{
+			{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+			{{2, 2}, {2, 3}, {3, 3}, {3, 2}, {2, 2}},
+		}),
+	}
+	fs[1].ID = "lake"
+	reports := InspectFeatures(fs)
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, 1, reports[1].Feature)
+	assert.Equal(t, 1, reports[1].Ring)
+	assert.Equal(t, "lake", reports[1].ID)
+	assert.Equal(t, "cw", reports[1].Winding)
+
+	assert.Equal(t, []RingReport{}, InspectFeatures(nil))
+}
+
+func TestInspectFeaturesSkipsWidth(t *testing.T) {
+	circle := func(segments int) *geojson.Feature {
+		return geojson.NewPolygonFeature([][][]float64{CirclePolygon(Point{}, 1000, segments)})
+	}
+	r := InspectRing(CirclePolygon(Point{}, 1000, MaxWidthVertices+1))
+	assert.True(t, r.Valid)
+	assert.True(t, r.WidthSkipped)
+	assert.Zero(t, r.MinWidth)
+	assert.True(t, r.LongestEdge > 0)
+
+	// the rings past the total are not measured either
+	var fs []*geojson.Feature
+	for i := 0; i < MaxWidthTotalVertices/MaxWidthVertices+1; i++ {
+		fs = append(fs, circle(MaxWidthVertices))
+	}
+	reports := InspectFeatures(fs)
+	for _, r := range reports[:len(reports)-1] {
+		assert.False(t, r.WidthSkipped)
+		assert.True(t, r.MinWidth > 0)
+	}
+	assert.True(t, reports[len(reports)-1].WidthSkipped)
+}
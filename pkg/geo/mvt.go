@@ -0,0 +1,212 @@
+package geo
+
+import (
+	"fmt"
+	"github.com/golang/geo/s2"
+	"math"
+)
+
+const (
+	// mvtExtent is the size of the tile grid the geometries are quantized to
+	mvtExtent = 4096
+	// mvtEdgeSegments is the number of segments each cell edge is split to, as geodesics are curved in web mercator
+	mvtEdgeSegments = 8
+	// mvtMaxLat is the latitude of the edges of the web mercator world
+	mvtMaxLat = 85.0511287798066
+)
+
+// mvt geometry commands
+const (
+	mvtMoveTo    = 1
+	mvtLineTo    = 2
+	mvtClosePath = 7
+)
+
+// CellUnionToMVT encodes the cells of the union intersecting the z/x/y tile to a mapbox vector tile
+// with a single cells layer, clipping the cells to the tile. Features have the cell id as id and
+// token and level properties.
+func CellUnionToMVT(cu s2.CellUnion, z, x, y int) ([]byte, error) {
+	if z < 0 || z > 30 {
+		return nil, fmt.Errorf("mvt: invalid zoom %d", z)
+	}
+	n := 1 << uint(z)
+	if x < 0 || x >= n || y < 0 || y >= n {
+		return nil, fmt.Errorf("mvt: tile %d/%d/%d out of range", z, x, y)
+	}
+	bound := tileRect(z, x, y)
+
+	var layer pbWriter
+	layer.uintField(15, 2)
+	layer.bytesField(1, []byte("cells"))
+
+	levels := make(map[int]uint64)
+	var values pbWriter
+	nValues := uint64(0)
+	for _, id := range cu {
+		cell := s2.CellFromCellID(id)
+		if !cell.RectBound().Intersects(bound) {
+			continue
+		}
+		ring := clipRing(tileRing(cell, z, x, y), 0, mvtExtent)
+		geometry := encodeMVTRing(ring)
+		if geometry == nil {
+			continue
+		}
+
+		level, ok := levels[id.Level()]
+		if !ok {
+			var v pbWriter
+			v.uintField(4, uint64(id.Level()))
+			values.bytesField(4, v.buf)
+			level, levels[id.Level()] = nValues, nValues
+			nValues++
+		}
+		var token pbWriter
+		token.bytesField(1, []byte(id.ToToken()))
+		values.bytesField(4, token.buf)
+		nValues++
+
+		var feature pbWriter
+		feature.uintField(1, uint64(id))
+		feature.packedUints(2, []uint64{0, nValues - 1, 1, level})
+		feature.uintField(3, 3)
+		feature.packedUints(4, geometry)
+		layer.bytesField(2, feature.buf)
+	}
+	layer.bytesField(3, []byte("token"))
+	layer.bytesField(3, []byte("level"))
+	layer.buf = append(layer.buf, values.buf...)
+	layer.uintField(5, mvtExtent)
+
+	var tile pbWriter
+	tile.bytesField(3, layer.buf)
+	return tile.buf, nil
+}
+
+// tileRing returns the vertices of the cell in the coordinates of the z/x/y tile grid. Longitudes are
+// unwrapped so that cells crossing the antimeridian stay contiguous, cells containing a pole are closed
+// along the edge of the web mercator world.
+func tileRing(cell s2.Cell, z, x, y int) [][2]float64 {
+	var lls [][2]float64
+	for k := 0; k < 4; k++ {
+		a, b := cell.Vertex(k), cell.Vertex((k+1)%4)
+		for i := 0; i < mvtEdgeSegments; i++ {
+			ll := s2.LatLngFromPoint(s2.Interpolate(float64(i)/mvtEdgeSegments, a, b))
+			lng := ll.Lng.Degrees()
+			if len(lls) > 0 {
+				prev := lls[len(lls)-1][0]
+				lng += 360 * math.Round((prev-lng)/360)
+			}
+			lls = append(lls, [2]float64{lng, math.Max(-mvtMaxLat, math.Min(mvtMaxLat, ll.Lat.Degrees()))})
+		}
+	}
+	first, last := lls[0][0], lls[len(lls)-1][0]
+	if math.Abs(first-last) > 180 {
+		// repeat the vertices one world further so that the ring covers every tile it touches
+		wrap := math.Copysign(360, last-first)
+		for _, ll := range lls[:len(lls):len(lls)] {
+			lls = append(lls, [2]float64{ll[0] + wrap, ll[1]})
+		}
+		pole := math.Copysign(mvtMaxLat, s2.LatLngFromPoint(cell.Center()).Lat.Degrees())
+		lls = append(lls, [2]float64{lls[len(lls)-1][0], pole}, [2]float64{first, pole})
+	}
+
+	n := float64(int(1) << uint(z))
+	ring := make([][2]float64, len(lls))
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for i, ll := range lls {
+		lat := ll[1] * math.Pi / 180
+		ring[i] = [2]float64{
+			(ll[0] + 180) / 360 * n,
+			(1 - math.Log(math.Tan(lat/2+math.Pi/4))/math.Pi) / 2 * n,
+		}
+		minX, maxX = math.Min(minX, ring[i][0]), math.Max(maxX, ring[i][0])
+	}
+
+	// shift the ring by whole worlds to the copy containing the tile, or else overlapping it
+	shift := 0.0
+	for _, k := range []float64{1, -1, 0} {
+		if minX+k*n <= float64(x+1) && maxX+k*n >= float64(x) {
+			shift = k * n
+		}
+	}
+	for _, k := range []float64{1, -1, 0} {
+		if minX+k*n <= float64(x) && maxX+k*n >= float64(x+1) {
+			shift = k * n
+		}
+	}
+	for i := range ring {
+		ring[i] = [2]float64{(ring[i][0] + shift - float64(x)) * mvtExtent, (ring[i][1] - float64(y)) * mvtExtent}
+	}
+	return ring
+}
+
+// clipRing clips the ring to the square from min to max with the Sutherland-Hodgman algorithm
+func clipRing(ring [][2]float64, min, max float64) [][2]float64 {
+	for axis := 0; axis < 2; axis++ {
+		for _, bound := range []float64{min, max} {
+			inside := func(p [2]float64) bool {
+				if bound == min {
+					return p[axis] >= min
+				}
+				return p[axis] <= max
+			}
+			var clipped [][2]float64
+			for i, cur := range ring {
+				prev := ring[(i+len(ring)-1)%len(ring)]
+				if inside(cur) != inside(prev) {
+					t := (bound - prev[axis]) / (cur[axis] - prev[axis])
+					var p [2]float64
+					p[axis] = bound
+					p[1-axis] = prev[1-axis] + t*(cur[1-axis]-prev[1-axis])
+					clipped = append(clipped, p)
+				}
+				if inside(cur) {
+					clipped = append(clipped, cur)
+				}
+			}
+			ring = clipped
+		}
+	}
+	return ring
+}
+
+// encodeMVTRing encodes the ring as exterior polygon ring geometry, clockwise in tile coordinates,
+// returning nil for rings with less than 3 distinct quantized vertices
+func encodeMVTRing(ring [][2]float64) []uint64 {
+	var pts [][2]int64
+	for _, p := range ring {
+		q := [2]int64{int64(math.Round(p[0])), int64(math.Round(p[1]))}
+		if len(pts) > 0 && pts[len(pts)-1] == q {
+			continue
+		}
+		pts = append(pts, q)
+	}
+	for len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+	if len(pts) < 3 {
+		return nil
+	}
+
+	area := int64(0)
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	if area == 0 {
+		return nil
+	}
+	if area < 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+
+	zigzag := func(v int64) uint64 { return uint64(v<<1 ^ v>>63) }
+	geometry := []uint64{mvtMoveTo | 1<<3, zigzag(pts[0][0]), zigzag(pts[0][1]), mvtLineTo | uint64(len(pts)-1)<<3}
+	for i := 1; i < len(pts); i++ {
+		geometry = append(geometry, zigzag(pts[i][0]-pts[i-1][0]), zigzag(pts[i][1]-pts[i-1][1]))
+	}
+	return append(geometry, mvtClosePath|1<<3)
+}
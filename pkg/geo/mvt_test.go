@@ -0,0 +1,57 @@
+package geo
+
+import (
+	"encoding/hex"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCellUnionToMVT(t *testing.T) {
+	empty, err := CellUnionToMVT(nil, 0, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "1a1a78020a0563656c6c731a05746f6b656e1a056c6576656c288020", hex.EncodeToString(empty))
+
+	// a cell around lat/lng 0, 0 is only in the tiles east and west of the meridian
+	id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(1, 1)).Parent(10)
+	cu := s2.CellUnion{id}
+	b, err := CellUnionToMVT(cu, 1, 1, 0)
+	assert.NoError(t, err)
+	assert.True(t, len(b) > len(empty))
+	b, err = CellUnionToMVT(cu, 1, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, empty, b)
+
+	// cells crossing the antimeridian and containing a pole are encoded
+	for _, token := range []string{"5", "7", "b"} {
+		b, err = CellUnionToMVT(s2.CellUnion{s2.CellIDFromToken(token)}, 0, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, len(b) > len(empty), token)
+	}
+
+	// the cell crossing the antimeridian is in both tiles along it
+	for _, x := range []int{0, 1} {
+		b, err = CellUnionToMVT(s2.CellUnion{s2.CellIDFromToken("7")}, 1, x, 0)
+		assert.NoError(t, err)
+		assert.True(t, len(b) > len(empty))
+	}
+
+	_, err = CellUnionToMVT(cu, 1, 2, 0)
+	assert.Error(t, err)
+	_, err = CellUnionToMVT(cu, -1, 0, 0)
+	assert.Error(t, err)
+}
+
+func TestClipRing(t *testing.T) {
+	ring := [][2]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}}
+	assert.Equal(t, [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}, clipRing(ring, 0, 100))
+	assert.Empty(t, clipRing(ring, 20, 100))
+}
+
+func TestEncodeMVTRing(t *testing.T) {
+	// counter clockwise in tile coordinates is reversed
+	square := [][2]float64{{0, 0}, {0, 2}, {2, 2}, {2, 0}}
+	assert.Equal(t, []uint64{9, 4, 0, 26, 0, 4, 3, 0, 0, 3, 15}, encodeMVTRing(square))
+	assert.Equal(t, []uint64{9, 4, 0, 26, 0, 4, 3, 0, 0, 3, 15}, encodeMVTRing([][2]float64{{2, 0}, {2, 2}, {0, 2}, {0, 0}}))
+	assert.Nil(t, encodeMVTRing([][2]float64{{0, 0}, {0.1, 0.1}, {0.2, 0}}))
+}
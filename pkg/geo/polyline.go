@@ -0,0 +1,136 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"sort"
+)
+
+// polylinePrecision is the scale of the coordinates of the standard precision 5 encoded polylines
+const polylinePrecision = 1e5
+
+// MaxLineCells is the max estimated number of cells CellsAlongLine lists for a line
+const MaxLineCells = 100000
+
+// DecodePolyline decodes a precision 5 encoded polyline to [lng, lat] positions, failing for characters
+// outside of the encoding and for a truncated trailing value or position
+func DecodePolyline(s string) ([][]float64, error) {
+	var positions [][]float64
+	var lat, lng int64
+	i := 0
+	next := func() (int64, error) {
+		var v uint64
+		for shift := uint(0); i < len(s) && shift < 64; shift += 5 {
+			if s[i] < '?' || s[i] > '~' {
+				return 0, fmt.Errorf("invalid polyline character %q at %d", s[i], i)
+			}
+			b := uint64(s[i]) - 63
+			i++
+			v |= (b & 0x1f) << shift
+			if b < 0x20 {
+				return int64(v>>1) ^ -int64(v&1), nil
+			}
+		}
+		return 0, errors.New("truncated polyline")
+	}
+	for i < len(s) {
+		dlat, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if i == len(s) {
+			return nil, errors.New("truncated polyline")
+		}
+		dlng, err := next()
+		if err != nil {
+			return nil, err
+		}
+		lat, lng = lat+dlat, lng+dlng
+		positions = append(positions, []float64{float64(lng) / polylinePrecision, float64(lat) / polylinePrecision})
+	}
+	return positions, nil
+}
+
+// CoverLine covers the line of [lng, lat] positions with cells between the levels
+func CoverLine(points [][]float64, maxLevel, minLevel int) s2.CellUnion {
+	line := s2.Polyline(ringPoints(points))
+	rc := newCoverer(maxLevel, minLevel, maxCells)
+	return rc.Covering(&line)
+}
+
+// CellsAlongLine returns the cells of the level the line of [lng, lat] positions passes through in the order
+// it enters them, a cell entered again later in the line is only listed at its first visit. It fails with
+// ErrTooManyCells when LineCellCount is over MaxLineCells.
+func CellsAlongLine(points [][]float64, level int) ([]s2.CellID, error) {
+	pts := ringPoints(points)
+	if len(pts) == 0 {
+		return nil, nil
+	}
+	if LineCellCount(points, level) > MaxLineCells {
+		return nil, ErrTooManyCells
+	}
+	seen := map[s2.CellID]bool{}
+	var cells []s2.CellID
+	visit := func(id s2.CellID) {
+		if !seen[id] {
+			seen[id] = true
+			cells = append(cells, id)
+		}
+	}
+
+	visit(s2.CellIDFromLatLng(s2.LatLngFromPoint(pts[0])).Parent(level))
+	rc := newCoverer(level, level, maxCells)
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		if a == b {
+			continue
+		}
+		segment := s2.Polyline{a, b}
+		var traversed []s2.CellID
+		entries := map[s2.CellID]s1.Angle{}
+		for _, id := range rc.Covering(&segment) {
+			// the covering may hold cells near the segment it does not pass through
+			if d, ok := entryDistance(s2.CellFromCellID(id), a, b); ok {
+				traversed = append(traversed, id)
+				entries[id] = d
+			}
+		}
+		sort.SliceStable(traversed, func(i, j int) bool { return entries[traversed[i]] < entries[traversed[j]] })
+		for _, id := range traversed {
+			visit(id)
+		}
+	}
+	return cells, nil
+}
+
+// LineCellCount estimates the number of cells of the level the line of [lng, lat] positions passes through,
+// the length of each segment divided by the average cell edge length of the level plus the cell it starts in
+func LineCellCount(points [][]float64, level int) float64 {
+	pts := ringPoints(points)
+	edge := s2.AvgEdgeMetric.Value(level)
+	n := 1.0
+	for i := 1; i < len(pts); i++ {
+		n += float64(pts[i-1].Distance(pts[i]))/edge + 1
+	}
+	return n
+}
+
+// entryDistance returns the distance along the segment ab from a to where it enters the cell, and false when
+// the segment does not pass through the cell
+func entryDistance(cell s2.Cell, a, b s2.Point) (s1.Angle, bool) {
+	if cell.ContainsPoint(a) {
+		return 0, true
+	}
+	d, ok := s1.InfAngle(), false
+	for k := 0; k < 4; k++ {
+		v0, v1 := cell.Vertex(k), cell.Vertex((k+1)%4)
+		if s2.EdgeOrVertexCrossing(a, b, v0, v1) {
+			if x := a.Distance(s2.Intersection(a, b, v0, v1)); x < d {
+				d, ok = x, true
+			}
+		}
+	}
+	return d, ok
+}
@@ -0,0 +1,87 @@
+package geo
+
+import (
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	// the example of the encoded polyline algorithm format documentation
+	line, err := DecodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}}, line)
+
+	line, err = DecodePolyline("")
+	assert.NoError(t, err)
+	assert.Empty(t, line)
+
+	// a trailing latitude without its longitude, a truncated value and characters outside of the encoding
+	_, err = DecodePolyline("_p~iF~ps|U_ulL")
+	assert.EqualError(t, err, "truncated polyline")
+	_, err = DecodePolyline("_p~iF~ps|U_ulLnnq")
+	assert.EqualError(t, err, "truncated polyline")
+	_, err = DecodePolyline("_p~iF ~ps|U")
+	assert.EqualError(t, err, "invalid polyline character ' ' at 5")
+}
+
+func TestCoverLine(t *testing.T) {
+	line := [][]float64{{0, 0}, {1, 1}}
+	cu := CoverLine(line, 12, 1)
+	assert.True(t, cu.IsValid())
+	assert.True(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.5, 0.5))))
+	assert.False(t, cu.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.9, 0.1))))
+}
+
+func TestCellsAlongLine(t *testing.T) {
+	// eastwards along the equator, then back through the same cells
+	line := [][]float64{{0.01, 0.01}, {1, 0.01}, {1, 0.2}, {0.01, 0.2}}
+	cells, err := CellsAlongLine(line, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.01, 0.01)).Parent(8), cells[0])
+
+	seen := map[s2.CellID]bool{}
+	for i, id := range cells {
+		assert.Equal(t, 8, id.Level())
+		assert.False(t, seen[id])
+		seen[id] = true
+		if i > 0 {
+			// consecutive cells of a single line share an edge or a vertex
+			adjacent := false
+			for _, nb := range cells[i-1].AllNeighbors(8) {
+				adjacent = adjacent || nb == id
+			}
+			assert.True(t, adjacent, id.ToToken())
+		}
+	}
+	covering := s2.CellUnion(append([]s2.CellID(nil), cells...))
+	covering.Normalize()
+	assert.Equal(t, CoverLine(line, 8, 8), covering)
+
+	// the cells of the first leg come first, ending at the cell of its last position
+	first, _ := CellsAlongLine(line[:2], 8)
+	assert.Equal(t, first, cells[:len(first)])
+	assert.Equal(t, s2.CellIDFromLatLng(s2.LatLngFromDegrees(0.01, 1)).Parent(8), first[len(first)-1])
+
+	// a diagonal walks through many cells, each one next to the previous
+	diagonal, _ := CellsAlongLine([][]float64{{0.01, 0.01}, {0.3, 0.2}}, 12)
+	assert.True(t, len(diagonal) > 20)
+	for i := 1; i < len(diagonal); i++ {
+		adjacent := false
+		for _, nb := range diagonal[i-1].AllNeighbors(12) {
+			adjacent = adjacent || nb == diagonal[i]
+		}
+		assert.True(t, adjacent, diagonal[i].ToToken())
+	}
+
+	cells, err = CellsAlongLine(nil, 8)
+	assert.NoError(t, err)
+	assert.Nil(t, cells)
+	cells, _ = CellsAlongLine([][]float64{{1, 1}, {1, 1}}, 8)
+	assert.Equal(t, 1, len(cells))
+	assert.True(t, LineCellCount(line[:2], 12) > 1)
+
+	// a thousand km at the leaf level is rejected before walking the line
+	_, err = CellsAlongLine([][]float64{{0, 0}, {10, 0}}, 30)
+	assert.Equal(t, ErrTooManyCells, err)
+}
@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"fmt"
+	"github.com/golang/geo/s2"
+)
+
+// Preset struct contains the region coverer parameters of a covering preset
+type Preset struct {
+	MinLevel int `json:"min_level"`
+	MaxLevel int `json:"max_level"`
+	MaxCells int `json:"max_cells"`
+	// MinCellArea is the area in square meters below which cells are merged to larger ancestors
+	MinCellArea float64 `json:"min_cell_area_sqm,omitempty"`
+}
+
+// Presets maps the preset names to coverer parameters
+var Presets = map[string]Preset{
+	// few large cells, quick to compute and store but loosely fitting
+	"fast": {MinLevel: 1, MaxLevel: 12, MaxCells: 20},
+	// the default parameters
+	"balanced": {MinLevel: 1, MaxLevel: 16, MaxCells: maxCells},
+	// many small cells, tightly fitting but slower and larger
+	"accurate": {MinLevel: 1, MaxLevel: 20, MaxCells: 500},
+}
+
+// LookupPreset returns the preset with the name
+func LookupPreset(name string) (Preset, error) {
+	pr, ok := Presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q", name)
+	}
+	return pr, nil
+}
+
+// CoverPolygonPreset converts s2 polygon to cell union with the coverer parameters of the preset, a
+// non positive MaxCells defaults to 100. Cells smaller than the MinCellArea are merged upwards.
+func CoverPolygonPreset(p *s2.Polygon, pr Preset) (s2.CellUnion, []string, [][][]float64) {
+	covering := CoverPolygonPresetIDs(p, pr)
+	tokens, s2cells := CellUnionTokens(covering)
+	return covering, tokens, s2cells
+}
+
+// CoverPolygonPresetIDs converts s2 polygon to cell union like CoverPolygonPreset without computing the
+// tokens and cells
+func CoverPolygonPresetIDs(p *s2.Polygon, pr Preset) s2.CellUnion {
+	if pr.MaxCells <= 0 {
+		pr.MaxCells = maxCells
+	}
+	rc := newCoverer(pr.MaxLevel, pr.MinLevel, pr.MaxCells)
+	covering := rc.Covering(s2.Region(p))
+	if pr.MinCellArea > 0 {
+		covering = MergeSmallCells(covering, pr.MinCellArea)
+	}
+	return covering
+}
@@ -0,0 +1,44 @@
+package geo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCoverPolygonPreset(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+
+	fast, err := LookupPreset("fast")
+	assert.NoError(t, err)
+	accurate, err := LookupPreset("accurate")
+	assert.NoError(t, err)
+
+	fastCu, fastTokens, fastCells := CoverPolygonPreset(p, fast)
+	accurateCu, _, _ := CoverPolygonPreset(p, accurate)
+	assert.True(t, len(fastCu) <= fast.MaxCells)
+	assert.Equal(t, len(fastCu), len(fastTokens))
+	assert.Equal(t, len(fastCu), len(fastCells))
+	assert.True(t, len(accurateCu) > len(fastCu))
+	assert.True(t, accurateCu.ExactArea() < fastCu.ExactArea())
+
+	cu, _, _, _ := CoverPolygon(p, 16, 1)
+	balancedCu, _, _ := CoverPolygonPreset(p, Presets["balanced"])
+	assert.Equal(t, cu, balancedCu)
+
+	_, err = LookupPreset("slow")
+	assert.Error(t, err)
+}
+
+func TestCoverPolygonPresetMinCellArea(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	cu, _, _ := CoverPolygonPreset(p, Preset{MinLevel: 1, MaxLevel: 14, MaxCells: 200, MinCellArea: 1e8})
+	for _, id := range cu {
+		assert.True(t, cellAreaSqm(id) >= 1e8)
+	}
+}
+
+func TestCoverPolygonPresetIDs(t *testing.T) {
+	p, _ := PointsToPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}})
+	cu, _, _ := CoverPolygonPreset(p, Presets["fast"])
+	assert.Equal(t, cu, CoverPolygonPresetIDs(p, Presets["fast"]))
+}
@@ -0,0 +1,304 @@
+package geo
+
+import (
+	"container/heap"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"math"
+)
+
+// SimplifyPolygon simplifies the ring with the Douglas-Peucker algorithm, dropping vertices closer than
+// toleranceMeters to the simplified outline. It may introduce self-intersections, see SimplifyPolygonSafe.
+func SimplifyPolygon(points [][]float64, toleranceMeters float64) [][]float64 {
+	if toleranceMeters <= 0 || len(points) < 5 {
+		return points
+	}
+	pts := ringPoints(points)
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	var simplify func(first, last int)
+	simplify = func(first, last int) {
+		maxDist, index := 0.0, -1
+		for i := first + 1; i < last; i++ {
+			if d := distanceMeters(pts[i], pts[first], pts[last]); d > maxDist {
+				maxDist, index = d, i
+			}
+		}
+		if index >= 0 && maxDist > toleranceMeters {
+			keep[index] = true
+			simplify(first, index)
+			simplify(index, last)
+		}
+	}
+	simplify(0, len(points)-1)
+
+	var simplified [][]float64
+	for i, pt := range points {
+		if keep[i] {
+			simplified = append(simplified, pt)
+		}
+	}
+	if len(simplified) < 4 {
+		return points
+	}
+	return simplified
+}
+
+// SimplifyPolygonSafe simplifies the ring like SimplifyPolygon while preserving its topology. Vertices are
+// removed one at a time, least significant first, and only when every original vertex the resulting edge
+// replaces stays within toleranceMeters of it and the edge does not cross any other edge of the ring, so the
+// ring never becomes self-intersecting, at the cost of simplifying less. A vertex whose edge crosses the ring
+// is reconsidered once its neighbours or the crossed edge change.
+func SimplifyPolygonSafe(points [][]float64, toleranceMeters float64) [][]float64 {
+	if toleranceMeters <= 0 || len(points) < 5 {
+		return points
+	}
+	closed := samePosition(points[0], points[len(points)-1])
+	ring := points
+	if closed {
+		ring = points[:len(points)-1]
+	}
+	pts := ringPoints(ring)
+	n := len(pts)
+	prev, next := make([]int, n), make([]int, n)
+	for i := range pts {
+		prev[i], next[i] = (i+n-1)%n, (i+1)%n
+	}
+
+	// deviation is the distance from the edge replacing the vertex i to the farthest original vertex it replaces
+	deviation := func(i int) float64 {
+		a, b := prev[i], next[i]
+		d := 0.0
+		for j := (a + 1) % n; j != b; j = (j + 1) % n {
+			d = math.Max(d, distanceMeters(pts[j], pts[a], pts[b]))
+		}
+		return d
+	}
+	removed := make([]bool, n)
+	version := make([]int, n)
+	q := &removalQueue{}
+	push := func(i int) {
+		if removed[i] {
+			return
+		}
+		version[i]++
+		if d := deviation(i); d <= toleranceMeters {
+			heap.Push(q, removal{vertex: i, deviation: d, version: version[i]})
+		}
+	}
+	for i := range pts {
+		push(i)
+	}
+
+	grid := newEdgeGrid(pts, next)
+	// blocked holds the vertices whose edge crossed the edge starting at the key vertex
+	blocked := map[int][]int{}
+	unblock := func(edge int) {
+		for _, i := range blocked[edge] {
+			push(i)
+		}
+		delete(blocked, edge)
+	}
+	for kept := n; kept > 3 && q.Len() > 0; {
+		r := heap.Pop(q).(removal)
+		i := r.vertex
+		if removed[i] || r.version != version[i] {
+			continue
+		}
+		a, b := prev[i], next[i]
+		if edge, ok := grid.crossing(pts, next, a, b, prev[a], a, i, b); ok {
+			blocked[edge] = append(blocked[edge], i)
+			continue
+		}
+		grid.remove(a)
+		grid.remove(i)
+		removed[i] = true
+		next[a], prev[b] = b, a
+		grid.add(pts, a, b)
+		kept--
+		unblock(a)
+		unblock(i)
+		push(a)
+		push(b)
+	}
+
+	simplified := make([][]float64, 0, n+1)
+	for i, pt := range ring {
+		if !removed[i] {
+			simplified = append(simplified, pt)
+		}
+	}
+	if closed {
+		simplified = append(simplified, simplified[0])
+	}
+	return simplified
+}
+
+// SmoothPolygon rounds the corners of the ring with the given iterations of Chaikin's algorithm, each one
+// replacing every edge by the points at a quarter and three quarters of it and so doubling the vertices.
+// The smoothed ring is closed, rings of less than 3 vertices or invalid coordinates are returned as given.
+func SmoothPolygon(points [][]float64, iterations int) [][]float64 {
+	for _, pt := range points {
+		if len(pt) < 2 {
+			return points
+		}
+	}
+	ring := points
+	if len(ring) > 1 && samePosition(ring[0], ring[len(ring)-1]) {
+		ring = ring[:len(ring)-1]
+	}
+	if iterations <= 0 || len(ring) < 3 {
+		return points
+	}
+
+	lerp := func(a, b []float64, f float64) []float64 {
+		v := []float64{a[0] + (b[0]-a[0])*f, a[1] + (b[1]-a[1])*f}
+		if len(a) > 2 && len(b) > 2 {
+			v = append(v, a[2]+(b[2]-a[2])*f)
+		}
+		return v
+	}
+	for i := 0; i < iterations; i++ {
+		smoothed := make([][]float64, 0, 2*len(ring))
+		for j, a := range ring {
+			b := ring[(j+1)%len(ring)]
+			smoothed = append(smoothed, lerp(a, b, 0.25), lerp(a, b, 0.75))
+		}
+		ring = smoothed
+	}
+	return append(ring, ring[0])
+}
+
+// ConvexHull returns the closed counterclockwise ring of the convex hull of the ring on the sphere, a cheap
+// always valid approximation of it. Rings of less than 3 distinct vertices or invalid coordinates, and rings
+// not contained by any hemisphere whose hull would be the full sphere, are returned as given.
+func ConvexHull(points [][]float64) [][]float64 {
+	for _, pt := range points {
+		if len(pt) < 2 {
+			return points
+		}
+	}
+	if distinctPositions(points, 3) < 3 {
+		return points
+	}
+	q := s2.NewConvexHullQuery()
+	for _, pt := range ringPoints(points) {
+		q.AddPoint(pt)
+	}
+	hull := q.ConvexHull()
+	if hull.IsFull() || hull.IsEmpty() {
+		return points
+	}
+	return loopToRing(hull, false)
+}
+
+func ringPoints(points [][]float64) []s2.Point {
+	pts := make([]s2.Point, len(points))
+	for i, pt := range points {
+		pts[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0]))
+	}
+	return pts
+}
+
+func distanceMeters(x, a, b s2.Point) float64 {
+	return s2.DistanceFromSegment(x, a, b).Radians() * EarthRadius * 1000
+}
+
+// removal is a candidate vertex removal of SimplifyPolygonSafe, stale once the vertex version changes
+type removal struct {
+	vertex    int
+	deviation float64
+	version   int
+}
+
+// removalQueue is a min heap of removals by deviation
+type removalQueue []removal
+
+func (q removalQueue) Len() int { return len(q) }
+
+func (q removalQueue) Less(i, j int) bool {
+	if q[i].deviation != q[j].deviation {
+		return q[i].deviation < q[j].deviation
+	}
+	return q[i].vertex < q[j].vertex
+}
+
+func (q removalQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *removalQueue) Push(x interface{}) { *q = append(*q, x.(removal)) }
+
+func (q *removalQueue) Pop() interface{} {
+	old := *q
+	r := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return r
+}
+
+// edgeGrid buckets the edges of a ring, identified by their first vertex, by the cells they pass through at
+// the level closest to the average edge length, so the edges an edge may cross are found without a full scan
+type edgeGrid struct {
+	coverer *s2.RegionCoverer
+	cells   map[s2.CellID][]int
+	edges   map[int][]s2.CellID
+}
+
+func newEdgeGrid(pts []s2.Point, next []int) *edgeGrid {
+	var length s1.Angle
+	for i, pt := range pts {
+		length += pt.Distance(pts[next[i]])
+	}
+	level := s2.AvgEdgeMetric.ClosestLevel(length.Radians() / float64(len(pts)))
+	g := &edgeGrid{coverer: newCoverer(level, level, maxCells), cells: map[s2.CellID][]int{}, edges: map[int][]s2.CellID{}}
+	for i := range pts {
+		g.add(pts, i, next[i])
+	}
+	return g
+}
+
+func (g *edgeGrid) add(pts []s2.Point, a, b int) {
+	cells := g.coverer.Covering(&s2.Polyline{pts[a], pts[b]})
+	for _, id := range cells {
+		g.cells[id] = append(g.cells[id], a)
+	}
+	g.edges[a] = cells
+}
+
+func (g *edgeGrid) remove(a int) {
+	for _, id := range g.edges[a] {
+		bucket := g.cells[id]
+		for k, e := range bucket {
+			if e == a {
+				bucket[k] = bucket[len(bucket)-1]
+				bucket = bucket[:len(bucket)-1]
+				break
+			}
+		}
+		if len(bucket) == 0 {
+			delete(g.cells, id)
+		} else {
+			g.cells[id] = bucket
+		}
+	}
+	delete(g.edges, a)
+}
+
+// crossing returns the first edge of the ring crossing the edge from a to b, skipping the given edges
+func (g *edgeGrid) crossing(pts []s2.Point, next []int, a, b int, skip ...int) (int, bool) {
+	seen := map[int]bool{}
+	for _, e := range skip {
+		seen[e] = true
+	}
+	for _, id := range g.coverer.Covering(&s2.Polyline{pts[a], pts[b]}) {
+		for _, e := range g.cells[id] {
+			if seen[e] {
+				continue
+			}
+			seen[e] = true
+			if s2.CrossingSign(pts[a], pts[b], pts[e], pts[next[e]]) != s2.DoNotCross {
+				return e, true
+			}
+		}
+	}
+	return 0, false
+}
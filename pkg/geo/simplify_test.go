@@ -0,0 +1,124 @@
+package geo
+
+import (
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+// removing the dip at (1, -0.001) within tolerance makes the bottom edge cross the vertex at (1, -0.0005)
+var notchedRing = [][]float64{{0, 0}, {1, -0.001}, {2, 0}, {2, 1}, {1, -0.0005}, {0, 1}, {0, 0.5}, {0, 0}}
+
+// selfIntersects checks if any two non adjacent edges of the closed ring cross
+func selfIntersects(ring [][]float64) bool {
+	pts := ringPoints(ring[:len(ring)-1])
+	n := len(pts)
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue
+			}
+			if s2.CrossingSign(pts[i], pts[(i+1)%n], pts[j], pts[(j+1)%n]) == s2.Cross {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestSimplifyPolygon(t *testing.T) {
+	assert.Equal(t, notchedRing, SimplifyPolygon(notchedRing, 0))
+
+	simplified := SimplifyPolygon(notchedRing, 200)
+	assert.Equal(t, [][]float64{{0, 0}, {2, 0}, {2, 1}, {1, -0.0005}, {0, 1}, {0, 0}}, simplified)
+	assert.True(t, selfIntersects(simplified))
+}
+
+func TestSimplifyPolygonSafe(t *testing.T) {
+	assert.False(t, selfIntersects(notchedRing))
+	assert.Equal(t, notchedRing, SimplifyPolygonSafe(notchedRing, 0))
+
+	simplified := SimplifyPolygonSafe(notchedRing, 200)
+	assert.Equal(t, [][]float64{{0, 0}, {1, -0.001}, {2, 0}, {2, 1}, {1, -0.0005}, {0, 1}, {0, 0}}, simplified)
+	assert.False(t, selfIntersects(simplified))
+}
+
+func TestSmoothPolygon(t *testing.T) {
+	square := [][]float64{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}
+	assert.Equal(t, square, SmoothPolygon(square, 0))
+
+	smoothed := SmoothPolygon(square, 1)
+	assert.Equal(t, [][]float64{{1, 0}, {3, 0}, {4, 1}, {4, 3}, {3, 4}, {1, 4}, {0, 3}, {0, 1}, {1, 0}}, smoothed)
+
+	// the open ring is smoothed the same and closed
+	assert.Equal(t, smoothed, SmoothPolygon(square[:4], 1))
+
+	smoothed = SmoothPolygon(square, 3)
+	assert.Equal(t, 4*8+1, len(smoothed))
+	assert.Equal(t, smoothed[0], smoothed[len(smoothed)-1])
+	assert.False(t, selfIntersects(smoothed))
+	for _, pt := range smoothed {
+		assert.True(t, pt[0] >= 0 && pt[0] <= 4 && pt[1] >= 0 && pt[1] <= 4)
+	}
+	p, err := PointsToPolygon(smoothed)
+	assert.NoError(t, err)
+	assert.True(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(2, 2))))
+
+	elevated := SmoothPolygon([][]float64{{0, 0, 10}, {4, 0, 30}, {4, 4, 30}, {0, 4, 10}, {0, 0, 10}}, 1)
+	assert.Equal(t, []float64{1, 0, 15}, elevated[0])
+
+	line := [][]float64{{0, 0}, {1, 1}, {0, 0}}
+	assert.Equal(t, line, SmoothPolygon(line, 2))
+	invalid := [][]float64{{0, 0}, {1}, {1, 1}, {0, 1}}
+	assert.Equal(t, invalid, SmoothPolygon(invalid, 2))
+}
+
+func TestConvexHull(t *testing.T) {
+	// a square with a notch at the top
+	notched := [][]float64{{0, 0}, {2, 0}, {2, 2}, {1, 1}, {0, 2}, {0, 0}}
+	hull := ConvexHull(notched)
+	assert.Equal(t, 5, len(hull))
+	assert.Equal(t, hull[0], hull[len(hull)-1])
+	for _, corner := range [][]float64{{0, 0}, {2, 0}, {2, 2}, {0, 2}} {
+		found := false
+		for _, pt := range hull {
+			found = found || math.Abs(pt[0]-corner[0]) < 1e-9 && math.Abs(pt[1]-corner[1]) < 1e-9
+		}
+		assert.True(t, found, corner)
+	}
+	p, err := PointsToPolygon(hull)
+	assert.NoError(t, err)
+	assert.True(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(1.5, 1))))
+
+	// the winding of the ring does not matter
+	reversed := make([][]float64, len(notched))
+	for i, pt := range notched {
+		reversed[len(notched)-1-i] = pt
+	}
+	assert.Equal(t, hull, ConvexHull(reversed))
+
+	line := [][]float64{{0, 0}, {1, 1}, {0, 0}}
+	assert.Equal(t, line, ConvexHull(line))
+	invalid := [][]float64{{0, 0}, {1}, {1, 1}, {0, 1}}
+	assert.Equal(t, invalid, ConvexHull(invalid))
+}
+
+func TestSimplifyPolygonSafeDeviation(t *testing.T) {
+	// removing the vertices one at a time, each within 100 meters of the edge replacing it, would move
+	// the outline over 130 meters away from the vertices removed first
+	ring := [][]float64{{0, 0.0002}, {0.4, 0.0012}, {0.8, 0.0015}, {1.2, 0.0011}, {1.6, 0}, {2, 0.0004}, {2, 1}, {0, 1}, {0, 0.0002}}
+
+	simplified := SimplifyPolygonSafe(ring, 100)
+	assert.True(t, len(simplified) < len(ring))
+	assert.False(t, selfIntersects(simplified))
+	// every original vertex stays within the tolerance of the simplified ring
+	pts := ringPoints(simplified)
+	for _, x := range ringPoints(ring) {
+		d := math.Inf(1)
+		for i := 1; i < len(pts); i++ {
+			d = math.Min(d, distanceMeters(x, pts[i-1], pts[i]))
+		}
+		assert.True(t, d <= 100, d)
+	}
+}
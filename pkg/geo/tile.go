@@ -0,0 +1,108 @@
+package geo
+
+import (
+	"errors"
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"math"
+)
+
+// Tile is the z/x/y web mercator tile of slippy maps
+type Tile struct {
+	Z int `json:"z"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// tileRect returns the lat/lng bounds of the z/x/y web mercator tile
+func tileRect(z, x, y int) s2.Rect {
+	n := float64(int(1) << uint(z))
+	lat := func(y float64) float64 {
+		return math.Atan(math.Sinh(math.Pi * (1 - 2*y/n)))
+	}
+	lng := func(x float64) float64 {
+		return x/n*2*math.Pi - math.Pi
+	}
+	return s2.Rect{
+		Lat: r1.Interval{Lo: lat(float64(y + 1)), Hi: lat(float64(y))},
+		Lng: s1.IntervalFromEndpoints(lng(float64(x)), lng(float64(x+1))),
+	}
+}
+
+// MaxTileZoom is the max zoom of the tiles listed by CellToTiles, the deepest zoom of common web maps
+const MaxTileZoom = 22
+
+// MaxTiles is the max number of tiles CellToTiles lists for a cell
+const MaxTiles = 100000
+
+// ErrTooManyTiles is returned when a cell overlaps more tiles than MaxTiles
+var ErrTooManyTiles = errors.New("too many tiles")
+
+// CellToTiles returns the tiles of the zoom overlapping the lat/lng bounds of the cell sorted by x and y,
+// tiles near the cell corners may overlap the bounds but not the cell. Cells wholly beyond the latitudes of
+// the web mercator world have no tiles, it fails with ErrTooManyTiles when TileCount is over MaxTiles.
+func CellToTiles(id s2.CellID, z int) ([]Tile, error) {
+	if count := TileCount(id, z); count > MaxTiles {
+		return nil, ErrTooManyTiles
+	}
+	columns, top, bottom := tileRanges(id, z)
+	var tiles []Tile
+	for _, r := range columns {
+		for x := r[0]; x <= r[1]; x++ {
+			for y := top; y <= bottom; y++ {
+				tiles = append(tiles, Tile{Z: z, X: x, Y: y})
+			}
+		}
+	}
+	return tiles, nil
+}
+
+// TileCount returns the number of tiles of the zoom CellToTiles lists for the cell without listing them
+func TileCount(id s2.CellID, z int) int {
+	columns, top, bottom := tileRanges(id, z)
+	count := 0
+	for _, r := range columns {
+		count += (r[1] - r[0] + 1) * (bottom - top + 1)
+	}
+	return count
+}
+
+// tileRanges returns the sorted, disjoint inclusive ranges of the tile columns and the range of the tile rows
+// of the zoom overlapping the lat/lng bounds of the cell, no columns for cells beyond the web mercator world
+func tileRanges(id s2.CellID, z int) ([][2]int, int, int) {
+	n := 1 << uint(z)
+	bound := s2.CellFromCellID(id).RectBound()
+	maxLat := mvtMaxLat * math.Pi / 180
+	if bound.Lat.Lo >= maxLat || bound.Lat.Hi <= -maxLat {
+		return nil, 0, -1
+	}
+
+	clamp := func(v float64) int {
+		return int(math.Max(0, math.Min(float64(n-1), math.Floor(v))))
+	}
+	tileX := func(lng float64) int {
+		return clamp((lng + math.Pi) / (2 * math.Pi) * float64(n))
+	}
+	tileY := func(lat float64) int {
+		lat = math.Max(-maxLat, math.Min(maxLat, lat))
+		return clamp((1 - math.Log(math.Tan(lat/2+math.Pi/4))/math.Pi) / 2 * float64(n))
+	}
+
+	var columns [][2]int
+	switch {
+	case bound.Lng.IsFull():
+		columns = [][2]int{{0, n - 1}}
+	case bound.Lng.IsInverted():
+		// the bounds cross the antimeridian, the ranges of both sides overlap at low zooms
+		lo, hi := tileX(bound.Lng.Lo), tileX(bound.Lng.Hi)
+		if lo <= hi+1 {
+			columns = [][2]int{{0, n - 1}}
+		} else {
+			columns = [][2]int{{0, hi}, {lo, n - 1}}
+		}
+	default:
+		columns = [][2]int{{tileX(bound.Lng.Lo), tileX(bound.Lng.Hi)}}
+	}
+	return columns, tileY(bound.Lat.Hi), tileY(bound.Lat.Lo)
+}
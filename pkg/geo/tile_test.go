@@ -0,0 +1,47 @@
+package geo
+
+import (
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCellToTiles(t *testing.T) {
+	tiles := func(id s2.CellID, z int) []Tile {
+		ts, err := CellToTiles(id, z)
+		assert.NoError(t, err)
+		assert.Equal(t, len(ts), TileCount(id, z))
+		return ts
+	}
+
+	// a small cell is in a single tile
+	id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(37.7749, -122.4194)).Parent(14)
+	assert.Equal(t, []Tile{{Z: 10, X: 163, Y: 395}}, tiles(id, 10))
+
+	// a cell on the equator and the meridian overlaps the tiles of all four quarters
+	id = s2.CellIDFromLatLng(s2.LatLngFromDegrees(0, 0)).Parent(2)
+	assert.ElementsMatch(t, []Tile{{1, 0, 0}, {1, 0, 1}, {1, 1, 0}, {1, 1, 1}}, tiles(id, 1))
+
+	// the face around the antimeridian is in the first and last columns
+	antimeridian := tiles(s2.CellIDFromFace(3), 2)
+	for _, tile := range antimeridian {
+		assert.True(t, tile.X == 0 || tile.X == 3)
+	}
+	assert.Equal(t, 0, antimeridian[0].X)
+
+	// a polar face covers all columns
+	assert.Equal(t, []Tile{{0, 0, 0}}, tiles(s2.CellIDFromFace(2), 0))
+	assert.Equal(t, 8, len(tiles(s2.CellIDFromFace(2), 2)))
+
+	// the cells around the pole span all longitudes, from level 5 they are beyond the web mercator world
+	pole := s2.CellIDFromLatLng(s2.LatLngFromDegrees(89.999, 10))
+	assert.Empty(t, tiles(pole.Parent(5), 12))
+
+	// a polar cell overlaps the top rows of all columns
+	ts := tiles(pole.Parent(4), 8)
+	assert.Equal(t, Tile{8, 0, 0}, ts[0])
+	assert.Equal(t, 255, ts[len(ts)-1].X)
+	assert.Equal(t, 256*(ts[len(ts)-1].Y+1), len(ts))
+	_, err := CellToTiles(pole.Parent(4), 11)
+	assert.Equal(t, ErrTooManyTiles, err)
+}
@@ -0,0 +1,191 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/paulmach/go.geojson"
+	"sort"
+)
+
+type topology struct {
+	Type      string                   `json:"type"`
+	Transform *topoTransform           `json:"transform"`
+	Objects   map[string]*topoGeometry `json:"objects"`
+	Arcs      [][][]float64            `json:"arcs"`
+}
+
+type topoTransform struct {
+	Scale     [2]float64 `json:"scale"`
+	Translate [2]float64 `json:"translate"`
+}
+
+type topoGeometry struct {
+	Type        string                 `json:"type"`
+	ID          interface{}            `json:"id"`
+	Properties  map[string]interface{} `json:"properties"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+	Arcs        json.RawMessage        `json:"arcs"`
+	Geometries  []*topoGeometry        `json:"geometries"`
+}
+
+// DecodeTopoJSON decodes a topology and converts its objects to geojson features (only polygons and points supported)
+func DecodeTopoJSON(data []byte) ([]*geojson.Feature, error) {
+	var t topology
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	if t.Type != "Topology" {
+		return nil, errors.New("not a topojson topology")
+	}
+
+	arcs := t.decodeArcs()
+
+	var names []string
+	for name := range t.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fs []*geojson.Feature
+	for _, name := range names {
+		objFs, err := t.features(t.Objects[name], arcs)
+		if err != nil {
+			return nil, fmt.Errorf("object %s: %v", name, err)
+		}
+		fs = append(fs, objFs...)
+	}
+	return fs, nil
+}
+
+// decodeArcs converts the quantized, delta encoded arcs of the topology to absolute positions
+func (t *topology) decodeArcs() [][][]float64 {
+	arcs := make([][][]float64, len(t.Arcs))
+	for i, arc := range t.Arcs {
+		var x, y float64
+		for _, pos := range arc {
+			if len(pos) < 2 {
+				continue
+			}
+			if t.Transform == nil {
+				arcs[i] = append(arcs[i], []float64{pos[0], pos[1]})
+				continue
+			}
+			x, y = x+pos[0], y+pos[1]
+			arcs[i] = append(arcs[i], []float64{
+				x*t.Transform.Scale[0] + t.Transform.Translate[0],
+				y*t.Transform.Scale[1] + t.Transform.Translate[1],
+			})
+		}
+	}
+	return arcs
+}
+
+func (t *topology) position(pos []float64) []float64 {
+	if t.Transform == nil || len(pos) < 2 {
+		return pos
+	}
+	return []float64{
+		pos[0]*t.Transform.Scale[0] + t.Transform.Translate[0],
+		pos[1]*t.Transform.Scale[1] + t.Transform.Translate[1],
+	}
+}
+
+func (t *topology) features(g *topoGeometry, arcs [][][]float64) ([]*geojson.Feature, error) {
+	if g == nil {
+		return nil, errors.New("null topojson geometry")
+	}
+	var geometry *geojson.Geometry
+
+	switch g.Type {
+	case "GeometryCollection":
+		var fs []*geojson.Feature
+		for _, child := range g.Geometries {
+			childFs, err := t.features(child, arcs)
+			if err != nil {
+				return nil, err
+			}
+			fs = append(fs, childFs...)
+		}
+		return fs, nil
+	case "Polygon":
+		var rings [][]int
+		if err := json.Unmarshal(g.Arcs, &rings); err != nil {
+			return nil, err
+		}
+		polygon, err := ringsFromArcs(rings, arcs)
+		if err != nil {
+			return nil, err
+		}
+		geometry = geojson.NewPolygonGeometry(polygon)
+	case "MultiPolygon":
+		var polygons [][][]int
+		if err := json.Unmarshal(g.Arcs, &polygons); err != nil {
+			return nil, err
+		}
+		var multi [][][][]float64
+		for _, rings := range polygons {
+			polygon, err := ringsFromArcs(rings, arcs)
+			if err != nil {
+				return nil, err
+			}
+			multi = append(multi, polygon)
+		}
+		geometry = geojson.NewMultiPolygonGeometry(multi...)
+	case "Point":
+		var pos []float64
+		if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+			return nil, err
+		}
+		geometry = geojson.NewPointGeometry(t.position(pos))
+	case "MultiPoint":
+		var positions [][]float64
+		if err := json.Unmarshal(g.Coordinates, &positions); err != nil {
+			return nil, err
+		}
+		for i, pos := range positions {
+			positions[i] = t.position(pos)
+		}
+		geometry = geojson.NewMultiPointGeometry(positions...)
+	default:
+		return nil, nil
+	}
+
+	f := geojson.NewFeature(geometry)
+	f.ID = g.ID
+	if g.Properties != nil {
+		f.Properties = g.Properties
+	}
+	return []*geojson.Feature{f}, nil
+}
+
+// ringsFromArcs stitches the referenced arcs to rings, a negative index ~i references arc i reversed
+func ringsFromArcs(rings [][]int, arcs [][][]float64) ([][][]float64, error) {
+	var polygon [][][]float64
+	for _, refs := range rings {
+		var ring [][]float64
+		for _, ref := range refs {
+			i, reversed := ref, false
+			if ref < 0 {
+				i, reversed = ^ref, true
+			}
+			if i >= len(arcs) {
+				return nil, fmt.Errorf("arc index %d out of range", i)
+			}
+			arc := arcs[i]
+			if reversed {
+				arc = make([][]float64, len(arcs[i]))
+				for j, pos := range arcs[i] {
+					arc[len(arc)-1-j] = pos
+				}
+			}
+			// consecutive arcs share their end and start position
+			if len(ring) > 0 && len(arc) > 0 {
+				arc = arc[1:]
+			}
+			ring = append(ring, arc...)
+		}
+		polygon = append(polygon, ring)
+	}
+	return polygon, nil
+}
@@ -0,0 +1,58 @@
+package geo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var validTopoJSON = []byte(`
+{
+  "type": "Topology",
+  "transform": {"scale": [0.5, 0.5], "translate": [10, 20]},
+  "objects": {
+    "zones": {
+      "type": "GeometryCollection",
+      "geometries": [
+        {"type": "Polygon", "id": "a", "properties": {"name": "A"}, "arcs": [[0, 1]]},
+        {"type": "Polygon", "arcs": [[-2, 2]]},
+        {"type": "Point", "coordinates": [4, 4]}
+      ]
+    }
+  },
+  "arcs": [
+    [[0, 0], [4, 0], [0, 4]],
+    [[4, 4], [-4, 0], [0, -4]],
+    [[4, 4], [0, -4], [-4, 0]]
+  ]
+}
+`)
+
+func TestDecodeTopoJSON(t *testing.T) {
+	fs, err := DecodeTopoJSON(validTopoJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(fs))
+
+	assert.True(t, fs[0].Geometry.IsPolygon())
+	assert.Equal(t, "a", fs[0].ID)
+	assert.Equal(t, "A", fs[0].Properties["name"])
+	assert.Equal(t, [][]float64{{10, 20}, {12, 20}, {12, 22}, {10, 22}, {10, 20}}, fs[0].Geometry.Polygon[0])
+
+	assert.Equal(t, [][]float64{{10, 20}, {10, 22}, {12, 22}, {12, 20}, {10, 20}}, fs[1].Geometry.Polygon[0])
+
+	assert.True(t, fs[2].Geometry.IsPoint())
+	assert.Equal(t, []float64{12, 22}, fs[2].Geometry.Point)
+
+	_, err = DecodeTopoJSON(validJSON)
+	assert.Error(t, err)
+}
+
+func TestDecodeTopoJSONNullObjects(t *testing.T) {
+	_, err := DecodeTopoJSON([]byte(`{"type":"Topology","objects":{"a":null},"arcs":[]}`))
+	assert.EqualError(t, err, "object a: null topojson geometry")
+
+	_, err = DecodeTopoJSON([]byte(`{"type":"Topology","objects":{"b":{"type":"GeometryCollection","geometries":[null]}},"arcs":[]}`))
+	assert.EqualError(t, err, "object b: null topojson geometry")
+
+	_, err = DecodeTopoJSON([]byte(`{"type":"Topology","objects":{"c":{"type":"Polygon","arcs":[[3]]}},"arcs":[]}`))
+	assert.EqualError(t, err, "object c: arc index 3 out of range")
+}
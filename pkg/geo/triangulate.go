@@ -0,0 +1,242 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paulmach/go.geojson"
+	"math"
+	"sort"
+)
+
+// Triangle holds the indices of the vertices of a triangle, counterclockwise in the lng/lat plane
+type Triangle [3]int
+
+// Tessellation is the triangulation of a polygon of a feature, the triangles index the vertices
+type Tessellation struct {
+	Feature   int         `json:"feature"`
+	ID        interface{} `json:"id,omitempty"`
+	Polygon   int         `json:"polygon"`
+	Vertices  [][]float64 `json:"vertices"`
+	Triangles []Triangle  `json:"triangles"`
+}
+
+// Triangulate tessellates the polygon of the outer ring and the holes by ear clipping in the lng/lat plane.
+// It returns the vertices of the rings in order without their closing vertices and the triangles indexing
+// them. The winding of the rings does not matter, the holes are bridged to the outer ring.
+func Triangulate(rings [][][]float64) ([][]float64, []Triangle, error) {
+	if len(rings) == 0 {
+		return nil, nil, ErrEmptyRing
+	}
+	var vertices [][]float64
+	var outer []int
+	var holes [][]int
+	for i, ring := range rings {
+		for _, pt := range ring {
+			if len(pt) < 2 {
+				return nil, nil, errors.New("invalid coordinate in ring")
+			}
+		}
+		if len(ring) > 1 && samePosition(ring[0], ring[len(ring)-1]) {
+			ring = ring[:len(ring)-1]
+		}
+		if distinctPositions(ring, 3) < 3 {
+			return nil, nil, fmt.Errorf("ring %d: %v", i, ErrDegenerateRing)
+		}
+
+		idx := make([]int, len(ring))
+		for j := range ring {
+			idx[j] = len(vertices) + j
+		}
+		vertices = append(vertices, ring...)
+		// the outer ring is clipped counterclockwise, so the holes are bridged into it clockwise
+		if (i == 0) != (ringArea(ring) > 0) {
+			for j, k := 0, len(idx)-1; j < k; j, k = j+1, k-1 {
+				idx[j], idx[k] = idx[k], idx[j]
+			}
+		}
+		if i == 0 {
+			outer = idx
+		} else {
+			holes = append(holes, idx)
+		}
+	}
+
+	// bridge the holes to the east, the easternmost first as the western bridges may run through earlier ones
+	maxX := func(hole []int) float64 {
+		x := math.Inf(-1)
+		for _, v := range hole {
+			x = math.Max(x, vertices[v][0])
+		}
+		return x
+	}
+	sort.SliceStable(holes, func(i, j int) bool { return maxX(holes[i]) > maxX(holes[j]) })
+	for i, hole := range holes {
+		var err error
+		if outer, err = bridgeHole(vertices, outer, hole); err != nil {
+			return nil, nil, fmt.Errorf("hole %d: %v", i+1, err)
+		}
+	}
+
+	return vertices, clipEars(vertices, outer), nil
+}
+
+// TriangulateFeatures tessellates each polygon of the polygon and multipolygon features
+func TriangulateFeatures(fs []*geojson.Feature) ([]Tessellation, error) {
+	tessellations := []Tessellation{}
+	for i, f := range fs {
+		for j, rings := range polygonParts(f.Geometry) {
+			vertices, triangles, err := Triangulate(rings)
+			if err != nil {
+				return nil, fmt.Errorf("feature %d polygon %d: %v", i, j, err)
+			}
+			tessellations = append(tessellations, Tessellation{Feature: i, ID: f.ID, Polygon: j, Vertices: vertices, Triangles: triangles})
+		}
+	}
+	return tessellations, nil
+}
+
+// bridgeHole splices the clockwise hole into the counterclockwise ring along a bridge from the easternmost
+// vertex of the hole to a ring vertex visible from it, the bridge ends appear twice in the returned ring
+func bridgeHole(vertices [][]float64, ring, hole []int) ([]int, error) {
+	m := 0
+	for i, v := range hole {
+		if vertices[v][0] > vertices[hole[m]][0] {
+			m = i
+		}
+	}
+	mx, my := vertices[hole[m]][0], vertices[hole[m]][1]
+
+	// the nearest ring edge crossed by the ray from m to the east
+	p, qx := -1, math.Inf(1)
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		a, b := vertices[ring[i]], vertices[ring[j]]
+		if a[1] == b[1] || (a[1] > my) == (b[1] > my) && a[1] != my && b[1] != my {
+			continue
+		}
+		x := a[0] + (my-a[1])*(b[0]-a[0])/(b[1]-a[1])
+		if x < mx || x >= qx {
+			continue
+		}
+		qx = x
+		switch {
+		case a[1] == my:
+			p = i
+		case b[1] == my:
+			p = j
+		case a[0] > b[0]:
+			p = i
+		default:
+			p = j
+		}
+	}
+	if p < 0 {
+		return nil, errors.New("hole is outside the outer ring")
+	}
+
+	// vertices visited twice since an earlier bridge are joined at the copy whose sector faces the hole
+	target := vertices[ring[p]]
+	for i, v := range ring {
+		if samePosition(vertices[v], target) && locallyInside(vertices, ring, i, vertices[hole[m]]) {
+			p = i
+			break
+		}
+	}
+
+	// ring vertices in the triangle of m, the crossing and p may block the view, take the one nearest to the ray
+	if px, py := target[0], target[1]; py != my {
+		a, b, c := []float64{mx, my}, []float64{qx, my}, []float64{px, py}
+		if cross(a, b, c) < 0 {
+			b, c = c, b
+		}
+		best := math.Inf(1)
+		for i, v := range ring {
+			pt := vertices[v]
+			if pt[0] < mx || samePosition(pt, target) || !inTriangle(a, b, c, pt) ||
+				!locallyInside(vertices, ring, i, vertices[hole[m]]) {
+				continue
+			}
+			if tan := math.Abs(my-pt[1]) / (pt[0] - mx); tan < best {
+				best, p = tan, i
+			}
+		}
+	}
+
+	bridged := make([]int, 0, len(ring)+len(hole)+2)
+	bridged = append(bridged, ring[:p+1]...)
+	bridged = append(bridged, hole[m:]...)
+	bridged = append(bridged, hole[:m+1]...)
+	return append(bridged, ring[p:]...), nil
+}
+
+// locallyInside checks if the direction from the ring vertex i to the point is inside the counterclockwise
+// ring near the vertex, between its next and previous edges
+func locallyInside(vertices [][]float64, ring []int, i int, pt []float64) bool {
+	n := len(ring)
+	prev, a, next := vertices[ring[(i+n-1)%n]], vertices[ring[i]], vertices[ring[(i+1)%n]]
+	if cross(prev, a, next) > 0 {
+		return cross(a, next, pt) > 0 && cross(a, prev, pt) < 0
+	}
+	return cross(a, prev, pt) <= 0 || cross(a, next, pt) >= 0
+}
+
+// clipEars triangulates the counterclockwise ring by repeatedly clipping a convex vertex whose triangle holds
+// no other vertex. Degenerate rings without such ears have their collinear vertices dropped, or else an
+// arbitrary vertex clipped, so that it always terminates.
+func clipEars(vertices [][]float64, ring []int) []Triangle {
+	ring = append([]int(nil), ring...)
+	var triangles []Triangle
+	for len(ring) >= 3 {
+		n := len(ring)
+		ear := -1
+		for i := 0; i < n && ear < 0; i++ {
+			if isEar(vertices, ring, i) {
+				ear = i
+			}
+		}
+		for i := 0; i < n && ear < 0; i++ {
+			if cross(vertices[ring[(i+n-1)%n]], vertices[ring[i]], vertices[ring[(i+1)%n]]) == 0 {
+				ear = i
+			}
+		}
+		if ear < 0 {
+			ear = 0
+		}
+		a, b, c := ring[(ear+n-1)%n], ring[ear], ring[(ear+1)%n]
+		if cross(vertices[a], vertices[b], vertices[c]) > 0 {
+			triangles = append(triangles, Triangle{a, b, c})
+		}
+		ring = append(ring[:ear], ring[ear+1:]...)
+	}
+	return triangles
+}
+
+// isEar checks if the ring vertex i is convex and no other vertex of the ring is in its triangle,
+// the copies of the triangle vertices made by the hole bridges aside
+func isEar(vertices [][]float64, ring []int, i int) bool {
+	n := len(ring)
+	a, b, c := vertices[ring[(i+n-1)%n]], vertices[ring[i]], vertices[ring[(i+1)%n]]
+	if cross(a, b, c) <= 0 {
+		return false
+	}
+	for j := 0; j < n-3; j++ {
+		pt := vertices[ring[(i+2+j)%n]]
+		if samePosition(pt, a) || samePosition(pt, b) || samePosition(pt, c) {
+			continue
+		}
+		if inTriangle(a, b, c, pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// cross returns the z of the cross product of ab and bc, positive when abc turns counterclockwise
+func cross(a, b, c []float64) float64 {
+	return (b[0]-a[0])*(c[1]-b[1]) - (b[1]-a[1])*(c[0]-b[0])
+}
+
+// inTriangle checks if the point is inside or on the edges of the counterclockwise triangle abc
+func inTriangle(a, b, c, pt []float64) bool {
+	return cross(a, b, pt) >= 0 && cross(b, c, pt) >= 0 && cross(c, a, pt) >= 0
+}
@@ -0,0 +1,85 @@
+package geo
+
+import (
+	"github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+// trianglesArea sums the planar areas of the triangles, failing for clockwise ones
+func trianglesArea(t *testing.T, vertices [][]float64, triangles []Triangle) float64 {
+	area := 0.0
+	for _, tr := range triangles {
+		a := cross(vertices[tr[0]], vertices[tr[1]], vertices[tr[2]]) / 2
+		assert.True(t, a > 0)
+		area += a
+	}
+	return area
+}
+
+func TestTriangulate(t *testing.T) {
+	// an L shape given clockwise
+	vertices, triangles, err := Triangulate([][][]float64{{{0, 0}, {0, 2}, {1, 2}, {1, 1}, {2, 1}, {2, 0}, {0, 0}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(vertices))
+	assert.Equal(t, 4, len(triangles))
+	assert.InDelta(t, 3, trianglesArea(t, vertices, triangles), 1e-9)
+
+	// a square with a hole
+	vertices, triangles, err = Triangulate([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, len(vertices))
+	assert.Equal(t, 8, len(triangles))
+	assert.InDelta(t, 96, trianglesArea(t, vertices, triangles), 1e-9)
+
+	// two holes, the western one bridged behind the eastern one
+	vertices, triangles, err = Triangulate([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{1, 4}, {3, 4}, {3, 6}, {1, 6}, {1, 4}},
+		{{5, 3}, {8, 3}, {8, 7}, {5, 7}, {5, 3}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 14, len(triangles))
+	assert.InDelta(t, 84, trianglesArea(t, vertices, triangles), 1e-9)
+
+	// collinear vertices
+	vertices, triangles, err = Triangulate([][][]float64{{{0, 0}, {1, 0}, {2, 0}, {2, 2}, {0, 2}}})
+	assert.NoError(t, err)
+	assert.InDelta(t, 4, trianglesArea(t, vertices, triangles), 1e-9)
+
+	_, _, err = Triangulate([][][]float64{{{0, 0}, {1, 1}, {0, 0}}})
+	assert.Error(t, err)
+	_, _, err = Triangulate([][][]float64{
+		{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+		{{5, 5}, {6, 5}, {6, 6}, {5, 5}},
+	})
+	assert.EqualError(t, err, "hole 1: hole is outside the outer ring")
+	_, _, err = Triangulate(nil)
+	assert.Equal(t, ErrEmptyRing, err)
+}
+
+func TestTriangulateFeatures(t *testing.T) {
+	fs := []*geojson.Feature{
+		geojson.NewPointFeature([]float64{0, 0}),
+		geojson.NewMultiPolygonFeature(
+			[][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}},
+			[][][]float64{{{5, 5}, {6, 5}, {5, 6}, {5, 5}}},
+		),
+	}
+	fs[1].ID = 3
+	ts, err := TriangulateFeatures(fs)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ts))
+	assert.Equal(t, 1, ts[1].Feature)
+	assert.Equal(t, 1, ts[1].Polygon)
+	assert.Equal(t, 3, ts[1].ID)
+	assert.Equal(t, []Triangle{{2, 0, 1}}, ts[1].Triangles)
+	assert.True(t, math.Abs(trianglesArea(t, ts[0].Vertices, ts[0].Triangles)-1) < 1e-9)
+
+	_, err = TriangulateFeatures([]*geojson.Feature{geojson.NewPolygonFeature([][][]float64{{{0, 0}, {1, 1}}})})
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package geo
+
+import (
+	"fmt"
+	"github.com/paulmach/go.geojson"
+	"strconv"
+	"strings"
+)
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+// DecodeWKT decodes a well-known text geometry (POINT, LINESTRING, POLYGON and their MULTI variants)
+func DecodeWKT(s string) (*geojson.Geometry, error) {
+	p := &wktParser{s: s}
+	name := strings.ToUpper(p.word())
+	if dim := strings.ToUpper(p.peekWord()); dim == "Z" || dim == "M" || dim == "ZM" {
+		p.word()
+	}
+
+	var g *geojson.Geometry
+	var err error
+
+	switch name {
+	case "POINT":
+		var pos [][]float64
+		if pos, err = p.positions(); err == nil {
+			if len(pos) != 1 {
+				return nil, fmt.Errorf("wkt: point must have exactly one position")
+			}
+			g = geojson.NewPointGeometry(pos[0])
+		}
+	case "LINESTRING":
+		var line [][]float64
+		if line, err = p.positions(); err == nil {
+			g = geojson.NewLineStringGeometry(line)
+		}
+	case "POLYGON":
+		var rings [][][]float64
+		if rings, err = p.rings(); err == nil {
+			g = geojson.NewPolygonGeometry(rings)
+		}
+	case "MULTIPOINT":
+		var pos [][]float64
+		if pos, err = p.multiPoint(); err == nil {
+			g = geojson.NewMultiPointGeometry(pos...)
+		}
+	case "MULTILINESTRING":
+		var lines [][][]float64
+		if lines, err = p.rings(); err == nil {
+			g = geojson.NewMultiLineStringGeometry(lines...)
+		}
+	case "MULTIPOLYGON":
+		var polygons [][][][]float64
+		if polygons, err = p.polygons(); err == nil {
+			g = geojson.NewMultiPolygonGeometry(polygons...)
+		}
+	default:
+		return nil, fmt.Errorf("wkt: unsupported geometry type %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("wkt: unexpected %q at %d", p.s[p.pos:], p.pos)
+	}
+	return g, nil
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *wktParser) peekWord() string {
+	start := p.pos
+	w := p.word()
+	p.pos = start
+	return w
+}
+
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] >= 'a' && p.s[p.pos] <= 'z' || p.s[p.pos] >= 'A' && p.s[p.pos] <= 'Z') {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// accept consumes c if it is the next non-space character
+func (p *wktParser) accept(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *wktParser) expect(c byte) error {
+	if !p.accept(c) {
+		return fmt.Errorf("wkt: expected %q at %d", c, p.pos)
+	}
+	return nil
+}
+
+// list parses a parenthesized, comma separated list calling item for each element
+func (p *wktParser) list(item func() error) error {
+	if strings.ToUpper(p.peekWord()) == "EMPTY" {
+		p.word()
+		return nil
+	}
+	if err := p.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		if !p.accept(',') {
+			break
+		}
+	}
+	return p.expect(')')
+}
+
+func (p *wktParser) position() ([]float64, error) {
+	var pos []float64
+	for {
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+			p.pos++
+		}
+		if start == p.pos {
+			break
+		}
+		v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("wkt: invalid number %q", p.s[start:p.pos])
+		}
+		pos = append(pos, v)
+	}
+	if len(pos) < 2 {
+		return nil, fmt.Errorf("wkt: expected coordinates at %d", p.pos)
+	}
+	return pos, nil
+}
+
+func (p *wktParser) positions() ([][]float64, error) {
+	var positions [][]float64
+	err := p.list(func() error {
+		pos, err := p.position()
+		positions = append(positions, pos)
+		return err
+	})
+	return positions, err
+}
+
+// multiPoint parses both the MULTIPOINT ((1 2), (3 4)) and MULTIPOINT (1 2, 3 4) forms
+func (p *wktParser) multiPoint() ([][]float64, error) {
+	var positions [][]float64
+	err := p.list(func() error {
+		var pos []float64
+		var err error
+		if p.accept('(') {
+			if pos, err = p.position(); err == nil {
+				err = p.expect(')')
+			}
+		} else {
+			pos, err = p.position()
+		}
+		positions = append(positions, pos)
+		return err
+	})
+	return positions, err
+}
+
+func (p *wktParser) rings() ([][][]float64, error) {
+	var rings [][][]float64
+	err := p.list(func() error {
+		ring, err := p.positions()
+		rings = append(rings, ring)
+		return err
+	})
+	return rings, err
+}
+
+func (p *wktParser) polygons() ([][][][]float64, error) {
+	var polygons [][][][]float64
+	err := p.list(func() error {
+		rings, err := p.rings()
+		polygons = append(polygons, rings)
+		return err
+	})
+	return polygons, err
+}
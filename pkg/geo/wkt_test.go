@@ -0,0 +1,48 @@
+package geo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecodeWKT(t *testing.T) {
+	g, err := DecodeWKT("POINT (30 10)")
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{30, 10}, g.Point)
+
+	g, err = DecodeWKT("LINESTRING (30 10, 10 30, 40 40)")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{30, 10}, {10, 30}, {40, 40}}, g.LineString)
+
+	g, err = DecodeWKT("polygon ((35 10, 45 45, 15 40, 10 20, 35 10), (20 30, 35 35, 30 20, 20 30))")
+	assert.NoError(t, err)
+	assert.True(t, g.IsPolygon())
+	assert.Equal(t, 2, len(g.Polygon))
+	assert.Equal(t, []float64{20, 30}, g.Polygon[1][0])
+
+	g, err = DecodeWKT("MULTIPOINT ((10 40), (40 30))")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{10, 40}, {40, 30}}, g.MultiPoint)
+
+	g, err = DecodeWKT("MULTIPOINT Z (10 40 1, 40 30 2)")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{10, 40, 1}, {40, 30, 2}}, g.MultiPoint)
+
+	g, err = DecodeWKT("MULTILINESTRING ((10 10, 20 20), (40 40, 30 30))")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(g.MultiLineString))
+
+	g, err = DecodeWKT("MULTIPOLYGON (((30 20, 45 40, 10 40, 30 20)), ((15 5, 40 10, 10 20, 5 10, 15 5)))")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(g.MultiPolygon))
+	assert.Equal(t, []float64{15, 5}, g.MultiPolygon[1][0][0])
+
+	_, err = DecodeWKT("POINT (30)")
+	assert.Error(t, err)
+	_, err = DecodeWKT("POLYGON ((30 10, 40 40)")
+	assert.Error(t, err)
+	_, err = DecodeWKT("CIRCLE (1 2)")
+	assert.Error(t, err)
+	_, err = DecodeWKT("POINT (1 2) foo")
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels in increasing severity
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses the case insensitive name of a level
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// Logger writes entries at or above its level as key=value lines
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+}
+
+// New creates a logger writing to out the entries at or above the level
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{out: out, level: level}
+}
+
+// SetLevel sets the minimum level of the written entries
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	l.level = level
+	l.mu.Unlock()
+}
+
+// Enabled checks if entries of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return level >= l.level
+}
+
+// Log writes the message with the alternating keys and values of kv if the level is enabled
+func (l *Logger) Log(level Level, msg string, kv ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("time=")
+	b.WriteString(time.Now().UTC().Format(time.RFC3339))
+	b.WriteString(" level=")
+	b.WriteString(level.String())
+	b.WriteString(" msg=")
+	b.WriteString(formatValue(msg))
+	for i := 0; i < len(kv); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(kv[i]))
+		b.WriteByte('=')
+		if i+1 < len(kv) {
+			b.WriteString(formatValue(fmt.Sprint(kv[i+1])))
+		}
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, b.String())
+}
+
+// formatValue quotes values which are empty or contain spaces, quotes or equal signs
+func formatValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+var std = New(os.Stderr, LevelInfo)
+
+// Default returns the logger used by the package level functions
+func Default() *Logger {
+	return std
+}
+
+// SetLevel sets the level of the default logger
+func SetLevel(level Level) {
+	std.SetLevel(level)
+}
+
+// Debug writes a debug entry with the default logger
+func Debug(msg string, kv ...interface{}) {
+	std.Log(LevelDebug, msg, kv...)
+}
+
+// Info writes an info entry with the default logger
+func Info(msg string, kv ...interface{}) {
+	std.Log(LevelInfo, msg, kv...)
+}
+
+// Warn writes a warn entry with the default logger
+func Warn(msg string, kv ...interface{}) {
+	std.Log(LevelWarn, msg, kv...)
+}
+
+// Error writes an error entry with the default logger
+func Error(msg string, kv ...interface{}) {
+	std.Log(LevelError, msg, kv...)
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("DEBUG")
+	assert.NoError(t, err)
+	assert.Equal(t, LevelDebug, l)
+	l, err = ParseLevel("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, LevelWarn, l)
+	assert.Equal(t, "warn", l.String())
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestLog(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, LevelInfo)
+
+	l.Log(LevelDebug, "hidden")
+	assert.Empty(t, out.String())
+
+	l.Log(LevelWarn, "repair", "action", "closed open ring", "count", 2, "empty", "")
+	assert.Regexp(t, `^time=\S+ level=warn msg=repair action="closed open ring" count=2 empty=""\n$`, out.String())
+
+	out.Reset()
+	l.SetLevel(LevelDebug)
+	assert.True(t, l.Enabled(LevelDebug))
+	l.Log(LevelDebug, "odd", "key")
+	assert.Contains(t, out.String(), "msg=odd key=\n")
+}